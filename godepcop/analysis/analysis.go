@@ -0,0 +1,17 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysis provides the godepcop analyzer, which checks imports
+// against the dependency rules in .godepcop files, for use by drivers such as
+// unitchecker, multichecker or "go vet".
+package analysis
+
+import (
+	"v.io/x/devtools/godepcop/internal/godepcop"
+)
+
+// Analyzer reports the imports that violate the .godepcop rules, the importers
+// rules of the imported packages, or the Go 1.5 internal package rule, at the
+// offending import spec.
+var Analyzer = godepcop.Analyzer
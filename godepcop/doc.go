@@ -10,13 +10,29 @@ Command godepcop checks Go package dependencies against constraints described in
 .godepcop files.  In addition to user-defined constraints, the Go 1.5 internal
 package rules are also enforced.
 
+Packages are resolved by running "go list" in the current directory, so module
+replace directives, workspaces and vendor directories are honored.
+
+Godepcop also runs as an analysis tool for "go vet", which reports direct
+imports that violate the constraints at the offending import:
+   go vet -vettool=$(which godepcop) <packages>
+
+Other analysis drivers can run it as the Analyzer in package
+v.io/x/devtools/godepcop/analysis.
+
 Usage:
    godepcop [flags] <command>
 
 The godepcop commands are:
    check          Check package dependency constraints
+   baseline       Manage the baseline of accepted violations
+   cycles         Find import cycles between groups of packages
+   diff           Report dependency changes between two git revisions
+   explain        Explain why a package may or may not import another package
+   init           Generate .godepcop files from the current imports
    list           List packages imported by the given packages
    list-importers List packages that import the given packages
+   stats          Report dependency statistics for the given packages
    help           Display help for commands or topics
 
 The global flags are:
@@ -32,29 +48,45 @@ Check package dependency constraints.
 Every Go package directory may contain an optional .godepcop file.  Each file
 specifies dependency rules, which either allow or deny imports by that package.
 The files are traversed hierarchically, from the deepmost package to the root of
-the source tree, until a matching rule is found.  If no matching rule is found,
+its module (or the root of the import path for packages outside of a module),
+until a matching rule is found.  If no matching rule is found,
 the default behavior is to allow the dependency, to support packages that do not
 have any dependency rules.
 
 The .godepcop file is encoded in XML:
 
   <godepcop>
+    <pkg deny="pattern1/*_internal" except="pattern1/log_internal"/>
     <pkg allow="pattern1/..."/>
     <pkg allow="pattern2"/>
     <pkg deny="..."/>
     <test allow="pattern3"/>
     <xtest allow="..."/>
+    <importers allow="pattern4/..."/>
+    <importers deny="..."/>
   </godepcop>
 
 Each element in godepcop is a rule, which either allows or denies imports based
-on the given pattern.  Patterns that end with "/..." are special: "foo/..."
-means that foo and all its subpackages match the rule.  The special-case pattern
-"..."  means that all packages in GOPATH, but not GOROOT, match the rule.
-
-There are three groups of rules:
-  pkg   - Rules applied to all imports from the package.
-  test  - Extra rules for imports from all test files.
-  xtest - Extra rules for imports from test files in the *_test package.
+on the given pattern.  Patterns are import paths that may contain wildcards:
+  *   - Matches any part of a single path element: "foo/*" matches foo/a but
+        not foo/a/b, and "foo/*_test" matches foo/a_test.
+  **  - As a whole path element, matches zero or more path elements: "foo/**"
+        matches foo and all its subpackages.  A "**" element may also appear at
+        the start or in the middle of a pattern, to match packages with a given
+        name or path suffix at any depth.
+  ... - As the last path element, matches the preceding path and all paths
+        below it: "foo/..." means that foo and all its subpackages match.
+The special-case pattern "..."  means that all packages, except for standard
+GOROOT packages, match the rule.
+
+A rule may also have an except attribute, holding space-separated patterns of
+packages that the rule doesn't apply to, even though they match its pattern.
+
+There are four groups of rules:
+  pkg       - Rules applied to all imports from the package.
+  test      - Extra rules for imports from all test files.
+  xtest     - Extra rules for imports from test files in the *_test package.
+  importers - Rules applied to all packages that import the package.
 
 Rules in each group are processed in the order they appear in the .godepcop
 file.  The transitive closure of the following imports are checked for each
@@ -63,11 +95,230 @@ package P:
   P.Imports+P.TestImports                - check test and pkg rules
   P.Imports+P.TestImports+P.XTestImports - check xtest, test and pkg rules
 
+The importers rules let a package restrict which packages may import it, much
+like the Go 1.5 internal package rule.  They are checked for each direct import
+D of package P, by matching P against the importers rules in the .godepcop files
+of D, traversed hierarchically from D in the same way.
+
+Architectural layers may also be declared, typically once at the root of the
+source tree:
+
+  <godepcop>
+    <layer name="lib" packages="pattern5/... pattern6/..."/>
+    <layer name="services" packages="pattern7/..."/>
+    <layer name="cmd" packages="pattern8/..."/>
+  </godepcop>
+
+Layers are ordered from the lowest to the highest, in the order they appear in
+the file, and each package belongs to the first layer with a matching pattern.
+A package may not import a package in a higher layer, regardless of any allow
+rules.  The layers are taken from the first .godepcop file that declares any,
+traversed hierarchically from the importing package, and are checked against the
+same transitive imports as the pkg, test and xtest rules.
+
+Violations are printed as text by default; set the -format flag to produce a
+machine-readable report.  Each violation records the importing and imported
+packages, the group of rules, the deciding rule and its config file, and a
+shortest import chain between the two packages.
+
+Packages are checked for the host platform by default.  Since imports may depend
+on build constraints, set the -platforms flag to check packages for each of the
+given platforms, and give the -tags flag several sets of build tags to check
+them with each set.  Each violation is then tagged with the platforms and tag
+sets where it occurs, e.g. linux/amd64+tags=a,b.
+
+Violations that are recorded in the baseline file are accepted, and don't cause
+the check to fail.  Baseline entries for the checked packages that no longer
+match a violation are reported as stale.  See "godepcop help baseline".
+
 Usage:
    godepcop check [flags] <packages>
 
 <packages> is a list of packages to check
 
+The godepcop check flags are:
+ -baseline=
+   Path of the baseline file with accepted violations.  Defaults to
+   .godepcop-baseline in the current directory, if it exists.
+ -cache-dir=
+   Directory of a persistent cache of check results, keyed by the modification
+   times of the Go and .godepcop files.  The cache is disabled if empty.
+ -format=text
+   Report violations with the given format:
+      text  - As human-readable text.
+      json  - As a JSON list of violation records.
+      sarif - As a SARIF 2.1.0 log, with locations relative to the current
+              directory.
+      xunit - As an xUnit report, with one test suite per package.
+ -num-workers=<runtime.NumCPU()>
+   Number of packages to check concurrently; use 1 to check packages serially.
+ -platforms=
+   Comma-separated list of GOOS/GOARCH platforms to check, e.g.
+   linux/amd64,android/arm.  Defaults to the host platform.
+ -tags=
+   Semicolon-separated list of sets of build tags, each a comma-separated list
+   of additional tags to consider satisfied while loading packages, e.g.
+   a,b;c.  Each platform is checked once with each set; an empty set, e.g. the
+   first one in ;c, checks without additional tags.
+
+Godepcop baseline - Manage the baseline of accepted violations
+
+Manage the baseline of accepted violations.
+
+The baseline file records existing violations that are accepted for now.  The
+check command only fails on violations that are not in the baseline, which
+allows new rules to be enforced without first fixing every existing violation.
+The baseline file is encoded in XML:
+
+  <godepcop-baseline>
+    <violation src="pkg1" dst="dep1" mode="pkg"/>
+    <violation src="pkg2" dst="dep2" mode="test"/>
+  </godepcop-baseline>
+
+Usage:
+   godepcop baseline [flags] <command>
+
+The godepcop baseline commands are:
+   update      Regenerate the baseline from the current violations
+
+Godepcop baseline update - Regenerate the baseline from the current violations
+
+Regenerate the baseline from the current violations.
+
+Checks the given <packages>, and replaces all baseline entries for those packages
+with their current violations.  Entries for other packages are retained.
+
+Usage:
+   godepcop baseline update [flags] <packages>
+
+<packages> is a list of packages to check
+
+The godepcop baseline update flags are:
+ -baseline=
+   Path of the baseline file with accepted violations.  Defaults to
+   .godepcop-baseline in the current directory, if it exists.
+ -cache-dir=
+   Directory of a persistent cache of check results, keyed by the modification
+   times of the Go and .godepcop files.  The cache is disabled if empty.
+ -num-workers=<runtime.NumCPU()>
+   Number of packages to check concurrently; use 1 to check packages serially.
+ -platforms=
+   Comma-separated list of GOOS/GOARCH platforms to check, e.g.
+   linux/amd64,android/arm.  Defaults to the host platform.
+ -tags=
+   Semicolon-separated list of sets of build tags, each a comma-separated list
+   of additional tags to consider satisfied while loading packages, e.g.
+   a,b;c.  Each platform is checked once with each set; an empty set, e.g. the
+   first one in ;c, checks without additional tags.
+
+Godepcop cycles - Find import cycles between groups of packages
+
+Find import cycles between groups of packages.
+
+Go doesn't allow import cycles between packages, but groups of packages, like
+directories or projects, may still import each other.  Such cycles make it hard
+to split the groups apart.
+
+Collapses the given <packages> and their transitive imports into groups, as
+specified by the -group-depth flag, and finds the strongly-connected components
+of the resulting graph.  Each component with more than one group is printed as a
+cycle, followed by the imports between the groups of the cycle, and the package
+imports behind each of them.
+
+Usage:
+   godepcop cycles [flags] <packages>
+
+<packages> is a list of packages
+
+The godepcop cycles flags are:
+ -goroot=false
+   Show $GOROOT packages.
+ -group-depth=0
+   Collapse packages into groups containing the packages with the same first N
+   import path elements.  Packages are not collapsed if N <= 0.
+ -test=false
+   Show imports from test files in the same package.
+ -xtest=false
+   Show imports from test files in the same package or in the *_test package.
+
+Godepcop diff - Report dependency changes between two git revisions
+
+Report dependency changes between two git revisions.
+
+Computes the transitive dependencies of the given <packages> at both <rev1> and
+<rev2>, and prints the packages and imports that were added or removed.  Each
+revision is checked out into a temporary git worktree, and the packages are
+resolved relative to the current directory within that worktree.  In GOPATH
+mode, the current directory must be in $GOPATH, and the worktree is checked out
+at the import path of the repository in a temporary GOPATH entry that takes
+precedence over $GOPATH.
+
+Elides $GOROOT packages by default; set the -goroot flag to include packages in
+$GOROOT.
+
+Usage:
+   godepcop diff [flags] <rev1> <rev2> <packages>
+
+<rev1> and <rev2> are git revisions of the repository containing the current
+directory, and <packages> is a list of packages.
+
+The godepcop diff flags are:
+ -format=text
+   Report the differences with the given format:
+      text - As human-readable text, with "+" and "-" prefixes.
+      json - As a JSON object.
+ -goroot=false
+   Show $GOROOT packages.
+ -test=false
+   Include imports from test files of the given packages.
+
+Godepcop explain - Explain why a package may or may not import another package
+
+Explain why package <pkg> may or may not import package <dep>.
+
+For each group of rules (pkg, test and xtest), prints every shortest chain of
+imports from <pkg> to <dep>, followed by a trace of the rule evaluation: each
+.godepcop file that was visited, each rule that was tried, and the rule that
+approved or rejected the dependency.
+
+If <pkg> directly imports <dep>, the importers rules of <dep> are traced as
+well.
+
+Usage:
+   godepcop explain [flags] <pkg> <dep>
+
+<pkg> is the importing package, and <dep> is the imported package
+
+Godepcop init - Generate .godepcop files from the current imports
+
+Generate a minimal .godepcop file in the directory of each of the given
+<packages>, which allows exactly the current transitive imports of the package,
+and denies all other imports.  Imports from test files are allowed by test and
+xtest rules.  Imports are collapsed into "/..." patterns where all of the known
+packages below a path in the same module, or below a path of at least two
+elements outside of modules, are imported.
+
+Existing .godepcop files are never overwritten.  The contents of each written
+file are printed.
+
+When the -tighten flag is set, the existing .godepcop files that apply to the
+given <packages> are rewritten instead, dropping the pkg, test and xtest allow
+rules that don't match any current import of those packages.  The dropped rules
+are removed from the files, which are otherwise left as they are, comments
+included.  Only the given <packages> are considered, so all packages below each
+.godepcop file should be given, e.g. by using the "/..." pattern.
+
+Usage:
+   godepcop init [flags] <packages>
+
+<packages> is a list of packages
+
+The godepcop init flags are:
+ -tighten=false
+   Rather than generating new .godepcop files, drop the allow rules that don't
+   match any current import from the existing .godepcop files that apply to the
+   given packages.
+
 Godepcop list - List packages imported by the given packages
 
 List packages imported by the given <packages>.
@@ -92,13 +343,18 @@ The godepcop list flags are:
    Only show direct dependencies, rather than showing transitive dependencies.
  -goroot=false
    Show $GOROOT packages.
- -merge-policies=+CCFLAGS,+CGO_CFLAGS,+CGO_CXXFLAGS,+CGO_LDFLAGS,+CXXFLAGS,GOARCH,GOOS,GOPATH:,^GOROOT*,+LDFLAGS,:PATH,VDLPATH:
-   specify policies for merging environment variables
  -style=set
    List dependencies with the given style:
-      set    - As a sorted set of unique packages.
-      indent - As a hierarchical list with pretty indentation.
-      dot    - As a DOT graph (http://www.graphviz.org)
+      set         - As a sorted set of unique packages.
+      indent      - As a hierarchical list with pretty indentation.
+      dot         - As a DOT graph (http://www.graphviz.org)
+      dot-cluster - As a DOT graph, with packages clustered by module or project,
+                    and GOROOT, third_party and internal packages highlighted.
+      json        - As a JSON graph, with a list of nodes and a list of edges.
+      mermaid     - As a Mermaid flowchart (https://mermaid.js.org)
+
+   Imports that only occur in test files are annotated with "test" or "xtest" by
+   the dot-cluster, json and mermaid styles.
  -test=false
    Show imports from test files in the same package.
  -xtest=false
@@ -128,8 +384,45 @@ The godepcop list-importers flags are:
    Only show direct dependencies, rather than showing transitive dependencies.
  -goroot=false
    Show $GOROOT packages.
- -merge-policies=+CCFLAGS,+CGO_CFLAGS,+CGO_CXXFLAGS,+CGO_LDFLAGS,+CXXFLAGS,GOARCH,GOOS,GOPATH:,^GOROOT*,+LDFLAGS,:PATH,VDLPATH:
-   specify policies for merging environment variables
+ -test=false
+   Show imports from test files in the same package.
+ -xtest=false
+   Show imports from test files in the same package or in the *_test package.
+
+Godepcop stats - Report dependency statistics for the given packages
+
+Report dependency statistics for the given <packages>, to find the packages that
+drag the most code into binaries.  For each package, reports:
+   direct      - The number of directly imported packages.
+   transitive  - The number of transitively imported packages.
+   importers   - The number of packages that transitively import the package,
+                 as reported by "list-importers".
+   files       - The number of Go and cgo files in the package and all of its
+                 transitive dependencies.
+   lines       - The number of lines in those files.
+   goroot      - The number of transitively imported $GOROOT packages.
+   third_party - The number of transitively imported third_party packages.
+   cgo         - The number of transitively imported packages that use cgo.
+
+Elides $GOROOT packages from all but the goroot column by default; set the
+-goroot flag to include them.
+
+Usage:
+   godepcop stats [flags] <packages>
+
+<packages> is a list of packages
+
+The godepcop stats flags are:
+ -format=text
+   Report the stats with the given format:
+      text - As a human-readable table.
+      json - As a JSON list of stats records.
+ -goroot=false
+   Show $GOROOT packages.
+ -sort=package
+   Sort the packages by the given column, one of package, direct, transitive,
+   importers, files, lines, goroot, third_party or cgo.  Packages are sorted by
+   name in ascending order, and by the other columns in descending order.
  -test=false
    Show imports from test files in the same package.
  -xtest=false
@@ -0,0 +1,109 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports the imports that violate the .godepcop rules, the importers
+// rules of the imported packages, or the Go 1.5 internal package rule.  Unlike
+// the check command, only direct imports are checked, and each violation is
+// reported at the offending import spec.
+var Analyzer = &analysis.Analyzer{
+	Name: "godepcop",
+	Doc:  "check imports against the dependency rules in .godepcop files",
+	Run:  runAnalyzer,
+}
+
+// IsVetTool returns true iff the command line args are those passed by
+// "go vet -vettool", in which case godepcop runs the analyzer.
+func IsVetTool(args []string) bool {
+	if len(args) != 1 {
+		return false
+	}
+	return args[0] == "-flags" || strings.HasPrefix(args[0], "-V=") || strings.HasSuffix(args[0], ".cfg")
+}
+
+func runAnalyzer(pass *analysis.Pass) (interface{}, error) {
+	if len(pass.Files) == 0 {
+		return nil, nil
+	}
+	// The package is loaded by directory, since the import path in the pass may
+	// refer to a test variant of the package.
+	dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+	pkgs, err := loadPackages(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: expected one package, got %d", dir, len(pkgs))
+	}
+	for _, file := range pass.Files {
+		name := pass.Fset.File(file.Pos()).Name()
+		diags, err := checkFileImports(pkgs[0], file, fileMode(name, file))
+		if err != nil {
+			return nil, err
+		}
+		for _, diag := range diags {
+			pass.Report(diag)
+		}
+	}
+	return nil, nil
+}
+
+// fileMode returns the mode in which the imports of the file are checked.
+func fileMode(name string, file *ast.File) checkMode {
+	switch {
+	case !strings.HasSuffix(name, "_test.go"):
+		return modePkg
+	case strings.HasSuffix(file.Name.Name, "_test"):
+		return modeXTest
+	}
+	return modeTest
+}
+
+// checkFileImports checks each import of file, which belongs to pkg, and
+// returns a diagnostic spanning the import spec for each violation.
+func checkFileImports(pkg *build.Package, file *ast.File, mode checkMode) ([]analysis.Diagnostic, error) {
+	var diags []analysis.Diagnostic
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		dep, err := importPackage(path)
+		if err != nil {
+			return nil, err
+		}
+		violations, err := checkDirectDep(pkg, dep, mode)
+		if err != nil {
+			return nil, err
+		}
+		v, err := checkDep(pkg, dep, mode)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			violations = append(violations, *v)
+		}
+		for _, v := range violations {
+			diags = append(diags, analysis.Diagnostic{
+				Pos:      spec.Pos(),
+				End:      spec.End(),
+				Category: "godepcop",
+				Message:  v.String(),
+			})
+		}
+	}
+	return diags, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsVetTool(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"check", "./..."}, false},
+		{[]string{"-flags"}, true},
+		{[]string{"-V=full"}, true},
+		{[]string{"/tmp/go-build/b001/vet.cfg"}, true},
+		{[]string{"list", "vet.cfg"}, false},
+	}
+	for _, test := range tests {
+		if got, want := IsVetTool(test.args), test.want; got != want {
+			t.Errorf("IsVetTool(%v) got %v, want %v", test.args, got, want)
+		}
+	}
+}
+
+func TestCheckFileImports(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	type diag struct {
+		line int
+		msg  string
+	}
+	tests := []struct {
+		path, file string
+		// want holds the line and a substring of the message of each diagnostic.
+		want []diag
+	}{
+		{"test-a", "test-a.go", nil},
+		{"test-c/child", "main.go", nil},
+		{"test-b", "main.go", []diag{{8, `violates pkg deny rule "fmt"`}}},
+		{"test-internal-fail", "main.go", []diag{{7, errGo15Internal.Error()}}},
+		{"test-visibility-fail", "main.go", []diag{{8, "violates importers deny rule"}}},
+		{"test-layers/lib/bad", "bad.go", []diag{{8, "layer order"}}},
+	}
+	for _, test := range tests {
+		pkg, err := importPackage(v + test.path)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, test.file), nil, parser.ImportsOnly)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		diags, err := checkFileImports(pkg, file, fileMode(test.file, file))
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		if got, want := len(diags), len(test.want); got != want {
+			t.Errorf("%v got %d diagnostics %v, want %d", test.path, got, diags, want)
+			continue
+		}
+		for i, d := range diags {
+			if got, want := fset.Position(d.Pos).Line, test.want[i].line; got != want {
+				t.Errorf("%v got line %d, want %d", test.path, got, want)
+			}
+			if got, want := d.Message, test.want[i].msg; !strings.Contains(got, want) {
+				t.Errorf("%v got message %q, want substring %q", test.path, got, want)
+			}
+		}
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		name, pkg string
+		want      checkMode
+	}{
+		{"a.go", "a", modePkg},
+		{"a_test.go", "a", modeTest},
+		{"a_test.go", "a_test", modeXTest},
+	}
+	for _, test := range tests {
+		file := &ast.File{Name: ast.NewIdent(test.pkg)}
+		if got, want := fileMode(test.name, file), test.want; got != want {
+			t.Errorf("fileMode(%q, %q) got %v, want %v", test.name, test.pkg, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"v.io/jiri/runutil"
+)
+
+const baselineFileName = ".godepcop-baseline"
+
+// baseline holds the set of accepted violations.  Violations that are in the
+// baseline don't cause the check to fail.
+type baseline struct {
+	XMLName    struct{}        `xml:"godepcop-baseline"`
+	Violations []baselineEntry `xml:"violation"`
+}
+
+// baselineEntry identifies an accepted violation.  The deciding rule isn't
+// recorded, so that the entry survives edits to the .godepcop files.
+type baselineEntry struct {
+	Src  string `xml:"src,attr"`
+	Dst  string `xml:"dst,attr"`
+	Mode string `xml:"mode,attr"`
+}
+
+func (e baselineEntry) String() string {
+	return fmt.Sprintf("%q importing %q (%s)", e.Src, e.Dst, e.Mode)
+}
+
+func (v violation) BaselineEntry() baselineEntry {
+	return baselineEntry{Src: v.Src.ImportPath, Dst: v.Dst.ImportPath, Mode: v.Mode.String()}
+}
+
+// loadBaseline loads the baseline file at the specified filesystem path.  If
+// the file doesn't exist, an empty baseline is returned, unless mustExist is
+// true.
+func loadBaseline(path string, mustExist bool) (*baseline, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if runutil.IsNotExist(err) && !mustExist {
+			return &baseline{}, nil
+		}
+		return nil, err
+	}
+	b := new(baseline)
+	if err := xml.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the baseline to the specified filesystem path.
+func (b *baseline) Save(path string) error {
+	sort.Sort(baselineSorter(b.Violations))
+	data, err := xml.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("MarshalIndent(%v) failed: %v", b, err)
+	}
+	data = append(data, '\n')
+	return ioutil.WriteFile(path, data, os.FileMode(0644))
+}
+
+// Filter returns the violations that are not in the baseline, along with the
+// stale baseline entries.  An entry is stale if its importing package is one of
+// the checked pkgs, but it no longer matches any violation.
+func (b *baseline) Filter(pkgs []*build.Package, violations []violation) ([]violation, []baselineEntry) {
+	accepted := make(map[baselineEntry]bool)
+	for _, e := range b.Violations {
+		accepted[e] = false
+	}
+	var fresh []violation
+	for _, v := range violations {
+		e := v.BaselineEntry()
+		if _, ok := accepted[e]; ok {
+			accepted[e] = true
+			continue
+		}
+		fresh = append(fresh, v)
+	}
+	checked := packageSet(pkgs)
+	var stale []baselineEntry
+	for _, e := range b.Violations {
+		if checked[e.Src] && !accepted[e] {
+			stale = append(stale, e)
+		}
+	}
+	return fresh, stale
+}
+
+// Update replaces all baseline entries for the checked pkgs with the given
+// violations.  Entries for other packages are retained.
+func (b *baseline) Update(pkgs []*build.Package, violations []violation) {
+	checked := packageSet(pkgs)
+	var entries []baselineEntry
+	for _, e := range b.Violations {
+		if !checked[e.Src] {
+			entries = append(entries, e)
+		}
+	}
+	seen := make(map[baselineEntry]bool)
+	for _, v := range violations {
+		if e := v.BaselineEntry(); !seen[e] {
+			seen[e] = true
+			entries = append(entries, e)
+		}
+	}
+	b.Violations = entries
+}
+
+func packageSet(pkgs []*build.Package) map[string]bool {
+	set := make(map[string]bool)
+	for _, pkg := range pkgs {
+		set[pkg.ImportPath] = true
+	}
+	return set
+}
+
+type baselineSorter []baselineEntry
+
+func (s baselineSorter) Len() int { return len(s) }
+func (s baselineSorter) Less(i, j int) bool {
+	if s[i].Src != s[j].Src {
+		return s[i].Src < s[j].Src
+	}
+	if s[i].Dst != s[j].Dst {
+		return s[i].Dst < s[j].Dst
+	}
+	return s[i].Mode < s[j].Mode
+}
+func (s baselineSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
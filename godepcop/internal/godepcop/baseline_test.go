@@ -0,0 +1,76 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBaselineFilter(t *testing.T) {
+	a, b, c := pkg("a"), pkg("b"), pkg("c")
+	violations := []violation{
+		{Src: a, Dst: b, Err: errGo15Internal, Mode: modePkg},
+		{Src: a, Dst: c, Err: errGo15Internal, Mode: modeTest},
+	}
+	bl := &baseline{Violations: []baselineEntry{
+		{Src: "a", Dst: "b", Mode: "pkg"},   // Accepted.
+		{Src: "a", Dst: "x", Mode: "pkg"},   // Stale.
+		{Src: "z", Dst: "x", Mode: "xtest"}, // Not checked, so not stale.
+	}}
+	fresh, stale := bl.Filter([]*build.Package{a, b}, violations)
+	if got, want := fresh, violations[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got fresh %v, want %v", got, want)
+	}
+	if got, want := stale, bl.Violations[1:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got stale %v, want %v", got, want)
+	}
+}
+
+func TestBaselineUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godepcop")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, baselineFileName)
+	// A missing baseline is only an error if it must exist.
+	if _, err := loadBaseline(path, true); err == nil {
+		t.Errorf("loadBaseline(%q, true) got nil error, want failure", path)
+	}
+	bl, err := loadBaseline(path, false)
+	if err != nil {
+		t.Fatalf("loadBaseline(%q, false) failed: %v", path, err)
+	}
+	bl.Violations = []baselineEntry{
+		{Src: "a", Dst: "old", Mode: "pkg"},
+		{Src: "z", Dst: "x", Mode: "xtest"},
+	}
+	a, b, c := pkg("a"), pkg("b"), pkg("c")
+	bl.Update([]*build.Package{a}, []violation{
+		{Src: a, Dst: c, Err: errGo15Internal, Mode: modeTest},
+		{Src: a, Dst: b, Err: errGo15Internal, Mode: modePkg},
+		{Src: a, Dst: b, Err: errGo15Internal, Mode: modePkg},
+	})
+	if err := bl.Save(path); err != nil {
+		t.Fatalf("Save(%q) failed: %v", path, err)
+	}
+	got, err := loadBaseline(path, true)
+	if err != nil {
+		t.Fatalf("loadBaseline(%q, true) failed: %v", path, err)
+	}
+	want := []baselineEntry{
+		{Src: "a", Dst: "b", Mode: "pkg"},
+		{Src: "a", Dst: "c", Mode: "test"},
+		{Src: "z", Dst: "x", Mode: "xtest"},
+	}
+	if !reflect.DeepEqual(got.Violations, want) {
+		t.Errorf("got %v, want %v", got.Violations, want)
+	}
+}
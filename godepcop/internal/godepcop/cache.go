@@ -0,0 +1,209 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"v.io/jiri/runutil"
+)
+
+// cacheVersion is part of every cache key; bump it whenever the check results
+// or their encoding change.
+const cacheVersion = "godepcop-cache-2"
+
+// checkCache is an on-disk cache of the violations of each checked package.
+// Entries are keyed by the modification times and sizes of the Go files of the
+// package and all of its dependencies, and of all .godepcop files that may
+// apply to them, so that entries become unreachable whenever any input of the
+// check changes.
+type checkCache struct {
+	dir string
+
+	mu           sync.Mutex
+	fingerprints map[string]string
+}
+
+func newCheckCache(dir string) *checkCache {
+	return &checkCache{dir: dir, fingerprints: make(map[string]string)}
+}
+
+// cachedViolation is the on-disk form of a violation.
+type cachedViolation struct {
+	Dst    string   `json:"dst"`
+	Mode   string   `json:"mode"`
+	Err    string   `json:"error"`
+	Rule   *rule    `json:"rule,omitempty"`
+	Config string   `json:"config,omitempty"`
+	Chain  []string `json:"chain"`
+}
+
+// Key returns the cache key of the check of pkg.
+func (c *checkCache) Key(pkg *build.Package) (string, error) {
+	deps := make(map[string]*build.Package)
+	if err := pkgImports.Deps(pkg, modeXTest.DepOpts(), deps); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", cacheVersion, loadPlatform, loadTags, pkg.ImportPath)
+	// Test files are only relevant for the checked package itself.
+	for _, files := range [][]string{pkg.TestGoFiles, pkg.XTestGoFiles} {
+		if err := hashFiles(h, pkg.Dir, files); err != nil {
+			return "", err
+		}
+	}
+	deps[pkg.ImportPath] = pkg
+	for _, dep := range sortPackages(deps) {
+		fp, err := c.fingerprint(dep)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", dep.ImportPath, fp)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprint returns a hash of the Go files of pkg and its .godepcop files.
+// $GOROOT packages are only identified by their import path.
+func (c *checkCache) fingerprint(pkg *build.Package) (string, error) {
+	if pkg.Goroot {
+		return "goroot", nil
+	}
+	c.mu.Lock()
+	fp, ok := c.fingerprints[pkg.ImportPath]
+	c.mu.Unlock()
+	if ok {
+		return fp, nil
+	}
+	h := sha256.New()
+	for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles} {
+		if err := hashFiles(h, pkg.Dir, files); err != nil {
+			return "", err
+		}
+	}
+	it := newConfigIter(pkg)
+	if it.err != nil {
+		return "", it.err
+	}
+	for dir, depth := it.dir, it.depth; depth >= 0; dir, depth = filepath.Dir(dir), depth-1 {
+		path := filepath.Join(dir, configFileName)
+		switch fi, err := os.Stat(path); {
+		case err == nil:
+			fmt.Fprintf(h, "%s %d %d\n", path, fi.ModTime().UnixNano(), fi.Size())
+		case !runutil.IsNotExist(err):
+			return "", err
+		}
+	}
+	fp = hex.EncodeToString(h.Sum(nil))
+	c.mu.Lock()
+	c.fingerprints[pkg.ImportPath] = fp
+	c.mu.Unlock()
+	return fp, nil
+}
+
+func hashFiles(h hash.Hash, dir string, files []string) error {
+	for _, file := range files {
+		fi, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d %d\n", file, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return nil
+}
+
+func (c *checkCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get returns the cached violations of pkg with the given key, and whether the
+// key was found in the cache.
+func (c *checkCache) Get(pkg *build.Package, key string) ([]violation, bool, error) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		if runutil.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var cached []cachedViolation
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false, fmt.Errorf("%s: %v", c.path(key), err)
+	}
+	var violations []violation
+	for _, cv := range cached {
+		v := violation{Src: pkg, Rule: cv.Rule, Config: cv.Config, Err: errors.New(cv.Err)}
+		if cv.Err == errGo15Internal.Error() {
+			v.Err = errGo15Internal
+		}
+		if v.Mode, err = parseCheckMode(cv.Mode); err != nil {
+			return nil, false, fmt.Errorf("%s: %v", c.path(key), err)
+		}
+		if v.Dst, err = importPackage(cv.Dst); err != nil {
+			return nil, false, err
+		}
+		for _, path := range cv.Chain {
+			p, err := importPackage(path)
+			if err != nil {
+				return nil, false, err
+			}
+			v.Chain = append(v.Chain, p)
+		}
+		violations = append(violations, v)
+	}
+	return violations, true, nil
+}
+
+// Put stores the violations of the package checked with the given key.
+func (c *checkCache) Put(key string, violations []violation) error {
+	cached := []cachedViolation{}
+	for _, v := range violations {
+		cv := cachedViolation{
+			Dst:    v.Dst.ImportPath,
+			Mode:   v.Mode.String(),
+			Err:    v.Err.Error(),
+			Rule:   v.Rule,
+			Config: v.Config,
+			Chain:  []string{},
+		}
+		for _, p := range v.Chain {
+			cv.Chain = append(cv.Chain, p.ImportPath)
+		}
+		cached = append(cached, cv)
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("Marshal(%v) failed: %v", cached, err)
+	}
+	if err := os.MkdirAll(c.dir, os.FileMode(0755)); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that concurrent runs never observe a
+	// partially written entry.
+	tmp, err := ioutil.TempFile(c.dir, "tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func violationStrings(violations []violation) []string {
+	var strs []string
+	for _, v := range violations {
+		strs = append(strs, v.String()+" "+v.Mode.String()+" "+v.Config+" "+chainString(v.Chain))
+	}
+	return strs
+}
+
+func TestCheckCache(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	dir, err := ioutil.TempDir("", "godepcop-cache")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pkg, err := importPackage(v + "test-b")
+	if err != nil {
+		t.Fatalf("importPackage failed: %v", err)
+	}
+	violations, err := checkDeps(pkg)
+	if err != nil {
+		t.Fatalf("checkDeps failed: %v", err)
+	}
+	if len(violations) == 0 {
+		t.Fatalf("got no violations")
+	}
+	c := newCheckCache(dir)
+	key, err := c.Key(pkg)
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	if _, ok, err := c.Get(pkg, key); ok || err != nil {
+		t.Fatalf("Get before Put got (%v, %v), want (false, nil)", ok, err)
+	}
+	if err := c.Put(key, violations); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, ok, err := c.Get(pkg, key)
+	if !ok || err != nil {
+		t.Fatalf("Get after Put got (%v, %v), want (true, nil)", ok, err)
+	}
+	if got, want := violationStrings(got), violationStrings(violations); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Touching a transitive dependency of the package invalidates the key.
+	dep, err := importPackage(v + "test-a")
+	if err != nil {
+		t.Fatalf("importPackage failed: %v", err)
+	}
+	file := filepath.Join(dep.Dir, dep.GoFiles[0])
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	defer os.Chtimes(file, fi.ModTime(), fi.ModTime())
+	if err := os.Chtimes(file, time.Now(), fi.ModTime().Add(time.Hour)); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	newKey, err := newCheckCache(dir).Key(pkg)
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	if newKey == key {
+		t.Errorf("got unchanged key %v after touching %v", key, file)
+	}
+}
@@ -0,0 +1,833 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package godepcop implements the godepcop command, which checks Go package
+// dependencies against the rules in .godepcop files.
+package godepcop
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"v.io/x/lib/cmdline"
+)
+
+var (
+	flagStyle      string
+	flagFormat     string
+	flagBaseline   string
+	flagGroupDepth int
+	flagSort       string
+	flagPlatforms  string
+	flagTags       string
+	flagNumWorkers int
+	flagCacheDir   string
+	flagTighten    bool
+	flagDirect     bool
+	flagGoroot     bool
+	flagTest       bool
+	flagXTest      bool
+)
+
+const (
+	styleSet        = "set"
+	styleIndent     = "indent"
+	styleDot        = "dot"
+	styleDotCluster = "dot-cluster"
+	styleJSON       = "json"
+	styleMermaid    = "mermaid"
+
+	descDirect = "Only show direct dependencies, rather than showing transitive dependencies."
+	descGoroot = "Show $GOROOT packages."
+	descTest   = "Show imports from test files in the same package."
+	descXTest  = "Show imports from test files in the same package or in the *_test package."
+
+	descDiffTest  = "Include imports from test files of the given packages."
+	descPlatforms = "Comma-separated list of GOOS/GOARCH platforms to check, e.g. linux/amd64,android/arm.  Defaults to the host platform."
+	descTags      = "Semicolon-separated list of sets of build tags, each a comma-separated list of additional tags to consider satisfied while loading packages, e.g. a,b;c.  Each platform is checked once with each set; an empty set, e.g. the first one in ;c, checks without additional tags."
+	descBaseline  = "Path of the baseline file with accepted violations.  Defaults to " + baselineFileName + " in the current directory, if it exists."
+	descWorkers   = "Number of packages to check concurrently; use 1 to check packages serially."
+	descCacheDir  = "Directory of a persistent cache of check results, keyed by the modification times of the Go and .godepcop files.  The cache is disabled if empty."
+)
+
+func init() {
+	cmdCheck.Flags.StringVar(&flagFormat, "format", formatText, `
+Report violations with the given format:
+   text  - As human-readable text.
+   json  - As a JSON list of violation records.
+   sarif - As a SARIF 2.1.0 log, with locations relative to the current
+           directory.
+   xunit - As an xUnit report, with one test suite per package.
+`)
+	cmdCheck.Flags.StringVar(&flagBaseline, "baseline", "", descBaseline)
+	cmdCheck.Flags.StringVar(&flagPlatforms, "platforms", "", descPlatforms)
+	cmdCheck.Flags.StringVar(&flagTags, "tags", "", descTags)
+	cmdCheck.Flags.IntVar(&flagNumWorkers, "num-workers", runtime.NumCPU(), descWorkers)
+	cmdCheck.Flags.Lookup("num-workers").DefValue = "<runtime.NumCPU()>"
+	cmdCheck.Flags.StringVar(&flagCacheDir, "cache-dir", "", descCacheDir)
+	cmdBaselineUpdate.Flags.StringVar(&flagBaseline, "baseline", "", descBaseline)
+	cmdBaselineUpdate.Flags.StringVar(&flagPlatforms, "platforms", "", descPlatforms)
+	cmdBaselineUpdate.Flags.StringVar(&flagTags, "tags", "", descTags)
+	cmdBaselineUpdate.Flags.IntVar(&flagNumWorkers, "num-workers", runtime.NumCPU(), descWorkers)
+	cmdBaselineUpdate.Flags.Lookup("num-workers").DefValue = "<runtime.NumCPU()>"
+	cmdBaselineUpdate.Flags.StringVar(&flagCacheDir, "cache-dir", "", descCacheDir)
+	cmdDiff.Flags.StringVar(&flagFormat, "format", formatText, `
+Report the differences with the given format:
+   text - As human-readable text, with "+" and "-" prefixes.
+   json - As a JSON object.
+`)
+	cmdDiff.Flags.BoolVar(&flagGoroot, "goroot", false, descGoroot)
+	cmdDiff.Flags.BoolVar(&flagTest, "test", false, descDiffTest)
+	cmdCycles.Flags.IntVar(&flagGroupDepth, "group-depth", 0, `
+Collapse packages into groups containing the packages with the same first N
+import path elements.  Packages are not collapsed if N <= 0.
+`)
+	cmdCycles.Flags.BoolVar(&flagGoroot, "goroot", false, descGoroot)
+	cmdCycles.Flags.BoolVar(&flagTest, "test", false, descTest)
+	cmdCycles.Flags.BoolVar(&flagXTest, "xtest", false, descXTest)
+	cmdInit.Flags.BoolVar(&flagTighten, "tighten", false, `
+Rather than generating new .godepcop files, drop the allow rules that don't
+match any current import from the existing .godepcop files that apply to the
+given packages.
+`)
+	cmdList.Flags.StringVar(&flagStyle, "style", styleSet, `
+List dependencies with the given style:
+   set         - As a sorted set of unique packages.
+   indent      - As a hierarchical list with pretty indentation.
+   dot         - As a DOT graph (http://www.graphviz.org)
+   dot-cluster - As a DOT graph, with packages clustered by module or project,
+                 and GOROOT, third_party and internal packages highlighted.
+   json        - As a JSON graph, with a list of nodes and a list of edges.
+   mermaid     - As a Mermaid flowchart (https://mermaid.js.org)
+
+Imports that only occur in test files are annotated with "test" or "xtest" by
+the dot-cluster, json and mermaid styles.
+`)
+	cmdList.Flags.BoolVar(&flagDirect, "direct", false, descDirect)
+	cmdList.Flags.BoolVar(&flagGoroot, "goroot", false, descGoroot)
+	cmdList.Flags.BoolVar(&flagTest, "test", false, descTest)
+	cmdList.Flags.BoolVar(&flagXTest, "xtest", false, descXTest)
+	cmdStats.Flags.StringVar(&flagFormat, "format", formatText, `
+Report the stats with the given format:
+   text - As a human-readable table.
+   json - As a JSON list of stats records.
+`)
+	cmdStats.Flags.StringVar(&flagSort, "sort", "package", `
+Sort the packages by the given column, one of package, direct, transitive,
+importers, files, lines, goroot, third_party or cgo.  Packages are sorted by
+name in ascending order, and by the other columns in descending order.
+`)
+	cmdStats.Flags.BoolVar(&flagGoroot, "goroot", false, descGoroot)
+	cmdStats.Flags.BoolVar(&flagTest, "test", false, descTest)
+	cmdStats.Flags.BoolVar(&flagXTest, "xtest", false, descXTest)
+	cmdListImporters.Flags.BoolVar(&flagDirect, "direct", false, descDirect)
+	cmdListImporters.Flags.BoolVar(&flagGoroot, "goroot", false, descGoroot)
+	cmdListImporters.Flags.BoolVar(&flagTest, "test", false, descTest)
+	cmdListImporters.Flags.BoolVar(&flagXTest, "xtest", false, descXTest)
+}
+
+func depOptsFromFlags() depOpts {
+	return depOpts{
+		DirectOnly:    flagDirect,
+		IncludeGoroot: flagGoroot,
+		IncludeTest:   flagTest,
+		IncludeXTest:  flagXTest,
+	}
+}
+
+// Root is the godepcop command.
+var Root = &cmdline.Command{
+	Name:  "godepcop",
+	Short: "Check Go package dependencies against user-defined rules",
+	Long: `
+Command godepcop checks Go package dependencies against constraints described in
+.godepcop files.  In addition to user-defined constraints, the Go 1.5 internal
+package rules are also enforced.
+
+Packages are resolved by running "go list" in the current directory, so module
+replace directives, workspaces and vendor directories are honored.
+
+Godepcop also runs as an analysis tool for "go vet", which reports direct
+imports that violate the constraints at the offending import:
+   go vet -vettool=$(which godepcop) <packages>
+
+Other analysis drivers can run it as the Analyzer in package
+v.io/x/devtools/godepcop/analysis.
+`,
+	Children: []*cmdline.Command{cmdCheck, cmdBaseline, cmdCycles, cmdDiff, cmdExplain, cmdInit, cmdList, cmdListImporters, cmdStats},
+}
+
+var cmdCheck = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runCheck),
+	Name:     "check",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages to check",
+	Short:    "Check package dependency constraints",
+	Long: `
+Check package dependency constraints.
+
+Every Go package directory may contain an optional .godepcop file.  Each file
+specifies dependency rules, which either allow or deny imports by that package.
+The files are traversed hierarchically, from the deepmost package to the root of
+its module (or the root of the import path for packages outside of a module),
+until a matching rule is found.  If no matching rule is found,
+the default behavior is to allow the dependency, to support packages that do not
+have any dependency rules.
+
+The .godepcop file is encoded in XML:
+
+  <godepcop>
+    <pkg deny="pattern1/*_internal" except="pattern1/log_internal"/>
+    <pkg allow="pattern1/..."/>
+    <pkg allow="pattern2"/>
+    <pkg deny="..."/>
+    <test allow="pattern3"/>
+    <xtest allow="..."/>
+    <importers allow="pattern4/..."/>
+    <importers deny="..."/>
+  </godepcop>
+
+Each element in godepcop is a rule, which either allows or denies imports based
+on the given pattern.  Patterns are import paths that may contain wildcards:
+  *   - Matches any part of a single path element: "foo/*" matches foo/a but
+        not foo/a/b, and "foo/*_test" matches foo/a_test.
+  **  - As a whole path element, matches zero or more path elements: "foo/**"
+        matches foo and all its subpackages.  A "**" element may also appear at
+        the start or in the middle of a pattern, to match packages with a given
+        name or path suffix at any depth.
+  ... - As the last path element, matches the preceding path and all paths
+        below it: "foo/..." means that foo and all its subpackages match.
+The special-case pattern "..."  means that all packages, except for standard
+GOROOT packages, match the rule.
+
+A rule may also have an except attribute, holding space-separated patterns of
+packages that the rule doesn't apply to, even though they match its pattern.
+
+There are four groups of rules:
+  pkg       - Rules applied to all imports from the package.
+  test      - Extra rules for imports from all test files.
+  xtest     - Extra rules for imports from test files in the *_test package.
+  importers - Rules applied to all packages that import the package.
+
+Rules in each group are processed in the order they appear in the .godepcop
+file.  The transitive closure of the following imports are checked for each
+package P:
+  P.Imports                              - check pkg rules
+  P.Imports+P.TestImports                - check test and pkg rules
+  P.Imports+P.TestImports+P.XTestImports - check xtest, test and pkg rules
+
+The importers rules let a package restrict which packages may import it, much
+like the Go 1.5 internal package rule.  They are checked for each direct import
+D of package P, by matching P against the importers rules in the .godepcop files
+of D, traversed hierarchically from D in the same way.
+
+Architectural layers may also be declared, typically once at the root of the
+source tree:
+
+  <godepcop>
+    <layer name="lib" packages="pattern5/... pattern6/..."/>
+    <layer name="services" packages="pattern7/..."/>
+    <layer name="cmd" packages="pattern8/..."/>
+  </godepcop>
+
+Layers are ordered from the lowest to the highest, in the order they appear in
+the file, and each package belongs to the first layer with a matching pattern.
+A package may not import a package in a higher layer, regardless of any allow
+rules.  The layers are taken from the first .godepcop file that declares any,
+traversed hierarchically from the importing package, and are checked against the
+same transitive imports as the pkg, test and xtest rules.
+
+Violations are printed as text by default; set the -format flag to produce a
+machine-readable report.  Each violation records the importing and imported
+packages, the group of rules, the deciding rule and its config file, and a
+shortest import chain between the two packages.
+
+Packages are checked for the host platform by default.  Since imports may depend
+on build constraints, set the -platforms flag to check packages for each of the
+given platforms, and give the -tags flag several sets of build tags to check
+them with each set.  Each violation is then tagged with the platforms and tag
+sets where it occurs, e.g. linux/amd64+tags=a,b.
+
+Violations that are recorded in the baseline file are accepted, and don't cause
+the check to fail.  Baseline entries for the checked packages that no longer
+match a violation are reported as stale.  See "godepcop help baseline".
+`}
+
+func runCheck(env *cmdline.Env, args []string) error {
+	switch flagFormat {
+	case formatText, formatJSON, formatSARIF, formatXUnit:
+	default:
+		return env.UsageErrorf("unknown -format %q", flagFormat)
+	}
+	pkgs, violations, err := checkPackages(args...)
+	if err != nil {
+		return err
+	}
+	// Drop violations that have been accepted in the baseline.
+	b, err := loadBaseline(baselinePath())
+	if err != nil {
+		return err
+	}
+	violations, stale := b.Filter(pkgs, violations)
+	for _, e := range stale {
+		fmt.Fprintf(env.Stderr, "stale baseline entry: %v no longer violates any rule\n", e)
+	}
+	if err := printViolations(env.Stdout, flagFormat, pkgs, violations); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("dependency violation")
+	}
+	return nil
+}
+
+// checkPackages loads the packages matching the given patterns, checks each of
+// them, and returns the packages along with all violations.  The packages are
+// checked for each platform specified by the -platforms flag, with each set of
+// tags specified by the -tags flag.
+func checkPackages(patterns ...string) ([]*build.Package, []violation, error) {
+	platforms, err := parsePlatforms(flagPlatforms)
+	if err != nil {
+		return nil, nil, err
+	}
+	return checkPlatforms(platforms, parseTagSets(flagTags), func() ([]*build.Package, []violation, error) {
+		// The cache memoizes fingerprints, which depend on the build context.
+		var cache *checkCache
+		if flagCacheDir != "" {
+			cache = newCheckCache(flagCacheDir)
+		}
+		return checkPackagesForContext(flagNumWorkers, cache, patterns...)
+	})
+}
+
+// checkPackagesForContext implements checkPackages for the current platform and
+// build tags, using numWorkers concurrent workers.  If cache is
+// non-nil, the results of each package are looked up in and stored in cache.
+func checkPackagesForContext(numWorkers int, cache *checkCache, patterns ...string) ([]*build.Package, []violation, error) {
+	// Gather packages specified in args.
+	pkgs, err := loadPackages(patterns...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Create a pool of workers.
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	tasks := make(chan int, len(pkgs))
+	taskResults := make(chan checkResult, len(pkgs))
+	for i := 0; i < numWorkers; i++ {
+		go checkWorker(pkgs, cache, tasks, taskResults)
+	}
+	// Distribute work to workers.
+	for index := range pkgs {
+		tasks <- index
+	}
+	close(tasks)
+	// Collect the results, in the order of the packages.
+	results := make([]checkResult, len(pkgs))
+	for range pkgs {
+		result := <-taskResults
+		results[result.index] = result
+	}
+	var violations []violation
+	for _, result := range results {
+		if result.err != nil {
+			return nil, nil, result.err
+		}
+		violations = append(violations, result.violations...)
+	}
+	return pkgs, violations, nil
+}
+
+type checkResult struct {
+	index      int
+	violations []violation
+	err        error
+}
+
+func checkWorker(pkgs []*build.Package, cache *checkCache, tasks <-chan int, results chan<- checkResult) {
+	for index := range tasks {
+		result := checkResult{index: index}
+		result.violations, result.err = checkCachedDeps(pkgs[index], cache)
+		results <- result
+	}
+}
+
+// checkCachedDeps returns the violations of pkg, from the cache if possible.
+func checkCachedDeps(pkg *build.Package, cache *checkCache) ([]violation, error) {
+	if cache == nil {
+		return checkDeps(pkg)
+	}
+	key, err := cache.Key(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok, err := cache.Get(pkg, key); ok || err != nil {
+		return v, err
+	}
+	v, err := checkDeps(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Put(key, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// baselinePath returns the path of the baseline file, and whether the file must
+// exist.  The default baseline file is optional.
+func baselinePath() (string, bool) {
+	if flagBaseline == "" {
+		return baselineFileName, false
+	}
+	return flagBaseline, true
+}
+
+var cmdBaseline = &cmdline.Command{
+	Name:  "baseline",
+	Short: "Manage the baseline of accepted violations",
+	Long: `
+Manage the baseline of accepted violations.
+
+The baseline file records existing violations that are accepted for now.  The
+check command only fails on violations that are not in the baseline, which
+allows new rules to be enforced without first fixing every existing violation.
+The baseline file is encoded in XML:
+
+  <godepcop-baseline>
+    <violation src="pkg1" dst="dep1" mode="pkg"/>
+    <violation src="pkg2" dst="dep2" mode="test"/>
+  </godepcop-baseline>
+`,
+	Children: []*cmdline.Command{cmdBaselineUpdate},
+}
+
+var cmdBaselineUpdate = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runBaselineUpdate),
+	Name:     "update",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages to check",
+	Short:    "Regenerate the baseline from the current violations",
+	Long: `
+Regenerate the baseline from the current violations.
+
+Checks the given <packages>, and replaces all baseline entries for those packages
+with their current violations.  Entries for other packages are retained.
+`}
+
+func runBaselineUpdate(env *cmdline.Env, args []string) error {
+	pkgs, violations, err := checkPackages(args...)
+	if err != nil {
+		return err
+	}
+	// The baseline file is created if it doesn't exist yet.
+	path, _ := baselinePath()
+	b, err := loadBaseline(path, false)
+	if err != nil {
+		return err
+	}
+	b.Update(pkgs, violations)
+	if err := b.Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "wrote %d baseline entries to %s\n", len(b.Violations), path)
+	return nil
+}
+
+var cmdCycles = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runCycles),
+	Name:     "cycles",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages",
+	Short:    "Find import cycles between groups of packages",
+	Long: `
+Find import cycles between groups of packages.
+
+Go doesn't allow import cycles between packages, but groups of packages, like
+directories or projects, may still import each other.  Such cycles make it hard
+to split the groups apart.
+
+Collapses the given <packages> and their transitive imports into groups, as
+specified by the -group-depth flag, and finds the strongly-connected components
+of the resulting graph.  Each component with more than one group is printed as a
+cycle, followed by the imports between the groups of the cycle, and the package
+imports behind each of them.
+`}
+
+func runCycles(env *cmdline.Env, args []string) error {
+	pkgs, err := loadPackages(args...)
+	if err != nil {
+		return err
+	}
+	cycles, err := findCycles(pkgs, depOptsFromFlags(), flagGroupDepth)
+	if err != nil {
+		return err
+	}
+	printCycles(env.Stdout, cycles)
+	return nil
+}
+
+var cmdDiff = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runDiff),
+	Name:     "diff",
+	ArgsName: "<rev1> <rev2> <packages>",
+	ArgsLong: `
+<rev1> and <rev2> are git revisions of the repository containing the current
+directory, and <packages> is a list of packages.
+`,
+	Short: "Report dependency changes between two git revisions",
+	Long: `
+Report dependency changes between two git revisions.
+
+Computes the transitive dependencies of the given <packages> at both <rev1> and
+<rev2>, and prints the packages and imports that were added or removed.  Each
+revision is checked out into a temporary git worktree, and the packages are
+resolved relative to the current directory within that worktree.  In GOPATH
+mode, the current directory must be in $GOPATH, and the worktree is checked out
+at the import path of the repository in a temporary GOPATH entry that takes
+precedence over $GOPATH.
+
+Elides $GOROOT packages by default; set the -goroot flag to include packages in
+$GOROOT.
+`}
+
+func runDiff(env *cmdline.Env, args []string) error {
+	if len(args) < 3 {
+		return env.UsageErrorf("expected <rev1> <rev2> <packages>, got %v", args)
+	}
+	switch flagFormat {
+	case formatText, formatJSON:
+	default:
+		return env.UsageErrorf("unknown -format %q", flagFormat)
+	}
+	before, err := loadDepGraphAtRev(args[0], args[2:], flagGoroot, flagTest)
+	if err != nil {
+		return err
+	}
+	after, err := loadDepGraphAtRev(args[1], args[2:], flagGoroot, flagTest)
+	if err != nil {
+		return err
+	}
+	return diffDepGraphs(before, after).Print(env.Stdout, flagFormat)
+}
+
+var cmdExplain = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runExplain),
+	Name:     "explain",
+	ArgsName: "<pkg> <dep>",
+	ArgsLong: "<pkg> is the importing package, and <dep> is the imported package",
+	Short:    "Explain why a package may or may not import another package",
+	Long: `
+Explain why package <pkg> may or may not import package <dep>.
+
+For each group of rules (pkg, test and xtest), prints every shortest chain of
+imports from <pkg> to <dep>, followed by a trace of the rule evaluation: each
+.godepcop file that was visited, each rule that was tried, and the rule that
+approved or rejected the dependency.
+
+If <pkg> directly imports <dep>, the importers rules of <dep> are traced as
+well.
+`}
+
+func runExplain(env *cmdline.Env, args []string) error {
+	if len(args) != 2 {
+		return env.UsageErrorf("expected <pkg> <dep>, got %v", args)
+	}
+	var pkgs []*build.Package
+	for _, arg := range args {
+		p, err := loadPackages(arg)
+		if err != nil {
+			return err
+		}
+		if len(p) != 1 {
+			return env.UsageErrorf("%q must match exactly one package, got %d", arg, len(p))
+		}
+		pkgs = append(pkgs, p[0])
+	}
+	return explainDep(env.Stdout, pkgs[0], pkgs[1])
+}
+
+var cmdInit = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runInit),
+	Name:     "init",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages",
+	Short:    "Generate .godepcop files from the current imports",
+	Long: `
+Generate a minimal .godepcop file in the directory of each of the given
+<packages>, which allows exactly the current transitive imports of the package,
+and denies all other imports.  Imports from test files are allowed by test and
+xtest rules.  Imports are collapsed into "/..." patterns where all of the known
+packages below a path in the same module, or below a path of at least two
+elements outside of modules, are imported.
+
+Existing .godepcop files are never overwritten.  The contents of each written
+file are printed.
+
+When the -tighten flag is set, the existing .godepcop files that apply to the
+given <packages> are rewritten instead, dropping the pkg, test and xtest allow
+rules that don't match any current import of those packages.  The dropped rules
+are removed from the files, which are otherwise left as they are, comments
+included.  Only the given <packages> are considered, so all packages below each
+.godepcop file should be given, e.g. by using the "/..." pattern.
+`,
+}
+
+func runInit(env *cmdline.Env, args []string) error {
+	pkgs, err := loadPackages(args...)
+	if err != nil {
+		return err
+	}
+	if flagTighten {
+		return tightenConfigs(env, pkgs)
+	}
+	// Gather all known packages, to decide which imports may be collapsed.
+	if _, err := loadPackages("all"); err != nil {
+		return err
+	}
+	universe := knownPackages()
+	for _, pkg := range pkgs {
+		if pkg.Goroot {
+			continue
+		}
+		path := filepath.Join(pkg.Dir, configFileName)
+		if fileExists(path) {
+			fmt.Fprintf(env.Stderr, "skipped %s: already exists\n", path)
+			continue
+		}
+		cfg, err := generateConfig(pkg, universe)
+		if err != nil {
+			return err
+		}
+		data, err := writeConfig(path, cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "wrote %s:\n%s", path, data)
+	}
+	return nil
+}
+
+func tightenConfigs(env *cmdline.Env, pkgs []*build.Package) error {
+	paths, governed, err := configsOf(pkgs)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		cached, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		// Don't modify the cached config.
+		cfg := *cached
+		dropped, err := tightenConfig(&cfg, governed[path])
+		if err != nil {
+			return err
+		}
+		if len(dropped) == 0 {
+			continue
+		}
+		for _, r := range dropped {
+			fmt.Fprintf(env.Stdout, "%s: dropped unused rule %v\n", path, r)
+		}
+		if len(cfg.PkgRules) == 0 && len(cfg.TestRules) == 0 && len(cfg.XTestRules) == 0 && len(cfg.ImporterRules) == 0 && len(cfg.Layers) == 0 {
+			// A config without rules is invalid.
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(env.Stdout, "removed %s\n", path)
+			continue
+		}
+		data, err := rewriteConfig(path, cached, &cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Stdout, "wrote %s:\n%s", path, data)
+	}
+	return nil
+}
+
+var cmdList = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runList),
+	Name:     "list",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages",
+	Short:    "List packages imported by the given packages",
+	Long: `
+List packages imported by the given <packages>.
+
+Lists all transitive imports by default; set the -direct flag to limit the
+listing to direct imports by the given <packages>.
+
+Elides $GOROOT packages by default; set the -goroot flag to include packages in
+$GOROOT.  If any of the given <packages> are $GOROOT packages, list behaves as
+if -goroot were set to true.
+
+Lists each imported package exactly once when using the default -style=set.  See
+the -style flag for alternate output styles.
+`}
+
+func runList(env *cmdline.Env, args []string) error {
+	// Gather packages specified in args.
+	pkgs, err := loadPackages(args...)
+	if err != nil {
+		return err
+	}
+	opts := depOptsFromFlags()
+	for _, pkg := range pkgs {
+		if pkg.Goroot {
+			// If any package in args is a GOROOT package, always include GOROOT deps.
+			opts.IncludeGoroot = true
+		}
+	}
+	switch flagStyle {
+	case styleIndent:
+		// Print indented deps for each package.
+		for _, pkg := range pkgs {
+			if err := opts.PrintIndent(env.Stdout, pkg); err != nil {
+				return err
+			}
+		}
+	case styleDot:
+		if err := printDot(env.Stdout, pkgs, opts); err != nil {
+			return err
+		}
+	case styleDotCluster, styleJSON, styleMermaid:
+		g, err := buildListGraph(pkgs, opts)
+		if err != nil {
+			return err
+		}
+		switch flagStyle {
+		case styleDotCluster:
+			printClusteredDot(env.Stdout, g)
+		case styleJSON:
+			return writeJSON(env.Stdout, g)
+		case styleMermaid:
+			printMermaid(env.Stdout, g)
+		}
+	default:
+		// Print deps for all combined packages.
+		deps := make(map[string]*build.Package)
+		for _, pkg := range pkgs {
+			if err := opts.Deps(pkg, deps); err != nil {
+				return err
+			}
+		}
+		for _, dep := range sortPackages(deps) {
+			fmt.Fprintln(env.Stdout, dep.ImportPath)
+		}
+	}
+	return nil
+}
+
+var cmdListImporters = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runListImporters),
+	Name:     "list-importers",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages",
+	Short:    "List packages that import the given packages",
+	Long: `
+List packages that import the given <packages>; the reverse of "list".  The
+listed packages are called "importers".
+
+Lists all transitive importers by default; set the -direct flag to limit the
+listing to importers that directly import the given <packages>.
+
+Elides $GOROOT packages by default; set the -goroot flag to include importers in
+$GOROOT.  If any of the given <packages> are $GOROOT packages, list-importers
+behaves as if -goroot were set to true.
+
+Lists each importer package exactly once.
+`}
+
+func runListImporters(env *cmdline.Env, args []string) error {
+	// Gather target packages specified in args.
+	targetPkgs, err := loadPackages(args...)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]*build.Package)
+	opts := depOptsFromFlags()
+	for _, pkg := range targetPkgs {
+		if pkg.Goroot {
+			// If any package in args is a GOROOT package, always include GOROOT deps.
+			opts.IncludeGoroot = true
+		}
+		targets[pkg.ImportPath] = pkg
+	}
+	// Gather all known packages.
+	allPkgs, err := loadPackages("all")
+	if err != nil {
+		return err
+	}
+	// Print every package that has dependencies that overlap with the targets.
+	matches := make(map[string]*build.Package)
+	for _, pkg := range allPkgs {
+		deps := make(map[string]*build.Package)
+		if err := opts.Deps(pkg, deps); err != nil {
+			return err
+		}
+		if hasOverlap(deps, targets) {
+			matches[pkg.ImportPath] = pkg
+		}
+	}
+	for _, pkg := range sortPackages(matches) {
+		fmt.Fprintln(env.Stdout, pkg.ImportPath)
+	}
+	return nil
+}
+
+var cmdStats = &cmdline.Command{
+	Runner:   cmdline.RunnerFunc(runStats),
+	Name:     "stats",
+	ArgsName: "<packages>",
+	ArgsLong: "<packages> is a list of packages",
+	Short:    "Report dependency statistics for the given packages",
+	Long: `
+Report dependency statistics for the given <packages>, to find the packages that
+drag the most code into binaries.  For each package, reports:
+   direct      - The number of directly imported packages.
+   transitive  - The number of transitively imported packages.
+   importers   - The number of packages that transitively import the package,
+                 as reported by "list-importers".
+   files       - The number of Go and cgo files in the package and all of its
+                 transitive dependencies.
+   lines       - The number of lines in those files.
+   goroot      - The number of transitively imported $GOROOT packages.
+   third_party - The number of transitively imported third_party packages.
+   cgo         - The number of transitively imported packages that use cgo.
+
+Elides $GOROOT packages from all but the goroot column by default; set the
+-goroot flag to include them.
+`,
+}
+
+func runStats(env *cmdline.Env, args []string) error {
+	switch flagFormat {
+	case formatText, formatJSON:
+	default:
+		return env.UsageErrorf("unknown -format %q", flagFormat)
+	}
+	if !isStatsColumn(flagSort) {
+		return env.UsageErrorf("unknown -sort column %q", flagSort)
+	}
+	pkgs, err := loadPackages(args...)
+	if err != nil {
+		return err
+	}
+	allPkgs, err := loadPackages("all")
+	if err != nil {
+		return err
+	}
+	stats, err := computeStats(pkgs, allPkgs, depOptsFromFlags())
+	if err != nil {
+		return err
+	}
+	sortStats(stats, flagSort)
+	return printStats(env.Stdout, flagFormat, stats)
+}
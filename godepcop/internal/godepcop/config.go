@@ -0,0 +1,273 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"v.io/jiri/runutil"
+)
+
+type config struct {
+	XMLName       struct{} `xml:"godepcop"`
+	PkgRules      []rule   `xml:"pkg"`
+	TestRules     []rule   `xml:"test"`
+	XTestRules    []rule   `xml:"xtest"`
+	ImporterRules []rule   `xml:"importers"`
+	Layers        []layer  `xml:"layer"`
+	Path          string   `xml:"-"`
+}
+
+// layer is a named architectural layer.  Layers are ordered by their position
+// in the config file, from the lowest to the highest layer.  Packages may only
+// import packages in the same or lower layers.
+type layer struct {
+	Name     string `xml:"name,attr"`
+	Packages string `xml:"packages,attr"` // Space-separated package patterns.
+}
+
+// Patterns returns the package patterns that belong to the layer.
+func (l layer) Patterns() []string {
+	return strings.Fields(l.Packages)
+}
+
+func (l layer) Validate() error {
+	switch {
+	case l.Name == "":
+		return errEmptyLayerName
+	case len(l.Patterns()) == 0:
+		return fmt.Errorf("%s: %v", l.Name, errEmptyLayer)
+	}
+	for _, pattern := range l.Patterns() {
+		if err := validatePattern(pattern); err != nil {
+			return fmt.Errorf("%s: %v", l.Name, err)
+		}
+	}
+	return nil
+}
+
+type rule struct {
+	// The fields are pointers so that we can distinguish empty from unset values.
+	Allow *string `xml:"allow,attr,omitempty"`
+	Deny  *string `xml:"deny,attr,omitempty"`
+	// Except holds space-separated patterns of packages that the rule doesn't
+	// apply to, even though they match the allow or deny pattern.
+	Except *string `xml:"except,attr,omitempty"`
+}
+
+func (r rule) IsDeny() bool {
+	return r.Deny != nil
+}
+
+func (r rule) Pattern() string {
+	switch {
+	case r.Allow != nil:
+		return *r.Allow
+	case r.Deny != nil:
+		return *r.Deny
+	}
+	return ""
+}
+
+// ExceptPatterns returns the patterns of packages excluded from the rule.
+func (r rule) ExceptPatterns() []string {
+	if r.Except == nil {
+		return nil
+	}
+	return strings.Fields(*r.Except)
+}
+
+func (r rule) String() string {
+	s := fmt.Sprintf("allow=%q", r.Pattern())
+	if r.IsDeny() {
+		s = fmt.Sprintf("deny=%q", r.Pattern())
+	}
+	if r.Except != nil {
+		s += fmt.Sprintf(" except=%q", *r.Except)
+	}
+	return s
+}
+
+func (r rule) Validate() error {
+	switch {
+	case r.Allow == nil && r.Deny == nil:
+		return errNeitherAllowDeny
+	case r.Allow != nil && r.Deny != nil:
+		return errBothAllowDeny
+	case r.Pattern() == "":
+		return errEmptyRule
+	case r.Except != nil && len(r.ExceptPatterns()) == 0:
+		return errEmptyExcept
+	}
+	if err := validatePattern(r.Pattern()); err != nil {
+		return err
+	}
+	for _, except := range r.ExceptPatterns() {
+		if err := validatePattern(except); err != nil {
+			return fmt.Errorf("except: %v", err)
+		}
+	}
+	return nil
+}
+
+// validatePattern returns an error describing why the package pattern is
+// malformed, or nil if it is valid.
+func validatePattern(pattern string) error {
+	_, err := patternRegexp(pattern)
+	return err
+}
+
+var (
+	configMu    sync.Mutex
+	configCache = map[string]*config{}
+)
+
+// loadConfig loads a .godepcop configuration file located at the specified
+// filesystem path.  If the call is successful, the output will be cached and
+// the same instance will be returned in subsequent calls.  It is safe to call
+// loadConfig concurrently.
+func loadConfig(path string) (*config, error) {
+	configMu.Lock()
+	p, ok := configCache[path]
+	configMu.Unlock()
+	if ok {
+		return p, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err = parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	p.Path = path
+	configMu.Lock()
+	defer configMu.Unlock()
+	// Another goroutine may have loaded the same config in the meantime.
+	if cached, ok := configCache[path]; ok {
+		return cached, nil
+	}
+	configCache[path] = p
+	return p, nil
+}
+
+var (
+	errBothAllowDeny    = errors.New("both allow and deny are specified")
+	errNeitherAllowDeny = errors.New("neither allow nor deny is specified")
+	errEmptyRule        = errors.New("empty rule")
+	errEmptyExcept      = errors.New("at least one except pattern must be specified")
+	errNoRules          = errors.New("at least one rule must be specified")
+	errEmptyLayerName   = errors.New("layer name must be specified")
+	errEmptyLayer       = errors.New("at least one package pattern must be specified")
+)
+
+func parseConfig(data []byte) (*config, error) {
+	c := new(config)
+	if err := xml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if len(c.PkgRules) == 0 && len(c.TestRules) == 0 && len(c.XTestRules) == 0 && len(c.ImporterRules) == 0 && len(c.Layers) == 0 {
+		return nil, errNoRules
+	}
+	for _, r := range c.PkgRules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("pkg: %v", err)
+		}
+	}
+	for _, r := range c.TestRules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("test: %v", err)
+		}
+	}
+	for _, r := range c.XTestRules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("xtest: %v", err)
+		}
+	}
+	for _, r := range c.ImporterRules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("importers: %v", err)
+		}
+	}
+	names := make(map[string]bool)
+	for _, l := range c.Layers {
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("layer: %v", err)
+		}
+		if names[l.Name] {
+			return nil, fmt.Errorf("layer: %s: duplicate layer name", l.Name)
+		}
+		names[l.Name] = true
+	}
+	return c, nil
+}
+
+type configIter struct {
+	cfg   *config
+	err   error
+	depth int
+	dir   string
+}
+
+const configFileName = ".godepcop"
+
+func (c *configIter) Advance() bool {
+	if c.depth < 0 {
+		return false
+	}
+	path := filepath.Join(c.dir, configFileName)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		if !runutil.IsNotExist(err) {
+			c.depth = -1
+			c.err = err
+			return false
+		}
+		cfg = &config{Path: path}
+	}
+	c.depth--
+	c.dir = filepath.Dir(c.dir)
+	c.cfg = cfg
+	return true
+}
+
+func (c *configIter) Value() *config { return c.cfg }
+func (c *configIter) Err() error     { return c.err }
+
+// newConfigIter returns an iterator over the .godepcop configuration files for
+// package p.  It starts at the config file in package p, and then travels up
+// successive directories until it reaches the root of the module containing p,
+// or the root of the import path if p isn't in a module.
+func newConfigIter(p *build.Package) *configIter {
+	if isPseudoPackage(p) {
+		return &configIter{depth: -1}
+	}
+	depth := strings.Count(p.ImportPath, "/")
+	pkgMu.Lock()
+	modDir := moduleDirs[p.ImportPath]
+	pkgMu.Unlock()
+	if modDir != "" {
+		rel, err := filepath.Rel(modDir, p.Dir)
+		if err != nil {
+			return &configIter{depth: -1, err: err}
+		}
+		depth = 0
+		if rel != "." {
+			depth = strings.Count(filepath.ToSlash(rel), "/") + 1
+		}
+	}
+	return &configIter{
+		dir:   p.Dir,
+		depth: depth,
+	}
+}
@@ -2,9 +2,10 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package godepcop
 
 import (
+	"go/build"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 
 var (
 	abc, xyz, dots = "abc", "xyz", "..."
+	globs, excepts = "abc/**/*_test", "abc/x abc/y/..."
 
 	testConfigXML = `
 <godepcop>
@@ -79,6 +81,18 @@ func TestParseConfig(t *testing.T) {
 			`<godepcop><pkg allow="abc"/><pkg deny="..."/></godepcop>`,
 			&config{PkgRules: []rule{{Allow: &abc}, {Deny: &dots}}},
 		},
+		{
+			`<godepcop><pkg deny="abc/**/*_test" except="abc/x abc/y/..."/></godepcop>`,
+			&config{PkgRules: []rule{{Deny: &globs, Except: &excepts}}},
+		},
+		{
+			`<godepcop><importers allow="abc"/><importers deny="..."/></godepcop>`,
+			&config{ImporterRules: []rule{{Allow: &abc}, {Deny: &dots}}},
+		},
+		{
+			`<godepcop><layer name="lib" packages="a/... b"/><layer name="cmd" packages="c/..."/></godepcop>`,
+			&config{Layers: []layer{{"lib", "a/... b"}, {"cmd", "c/..."}}},
+		},
 		{
 			testConfigXML,
 			testConfig,
@@ -133,6 +147,38 @@ func TestParseConfigError(t *testing.T) {
 			`<godepcop><pkg allow="x" deny="y"/></godepcop>`,
 			"pkg: both allow and deny are specified",
 		},
+		{
+			`<godepcop><pkg allow="a//b"/></godepcop>`,
+			`pkg: "a//b": empty path element`,
+		},
+		{
+			`<godepcop><pkg allow="a/.../b"/></godepcop>`,
+			`pkg: "a/.../b": "..." must be the last path element`,
+		},
+		{
+			`<godepcop><pkg allow="a/b..."/></godepcop>`,
+			`pkg: "a/b...": "..." must be a whole path element`,
+		},
+		{
+			`<godepcop><pkg allow="a/**/..."/></godepcop>`,
+			`pkg: "a/**/...": "..." may not follow "**"`,
+		},
+		{
+			`<godepcop><pkg allow="a/b**"/></godepcop>`,
+			`pkg: "a/b**": "**" must be a whole path element`,
+		},
+		{
+			`<godepcop><pkg allow="a/**/**/b"/></godepcop>`,
+			`pkg: "a/**/**/b": "**" may not follow "**"`,
+		},
+		{
+			`<godepcop><pkg deny="a/..." except=" "/></godepcop>`,
+			"pkg: at least one except pattern must be specified",
+		},
+		{
+			`<godepcop><pkg deny="a/..." except="a/b a/.../c"/></godepcop>`,
+			`pkg: except: "a/.../c": "..." must be the last path element`,
+		},
 		// Test rules
 		{
 			`<godepcop><test/></godepcop>`,
@@ -175,6 +221,36 @@ func TestParseConfigError(t *testing.T) {
 			`<godepcop><xtest allow="x" deny="y"/></godepcop>`,
 			"xtest: both allow and deny are specified",
 		},
+		// Importers rules
+		{
+			`<godepcop><importers/></godepcop>`,
+			"importers: neither allow nor deny is specified",
+		},
+		{
+			`<godepcop><importers allow=""/></godepcop>`,
+			"importers: empty rule",
+		},
+		{
+			`<godepcop><importers allow="x" deny="y"/></godepcop>`,
+			"importers: both allow and deny are specified",
+		},
+		// Layers
+		{
+			`<godepcop><layer packages="a"/></godepcop>`,
+			"layer: layer name must be specified",
+		},
+		{
+			`<godepcop><layer name="lib" packages=" "/></godepcop>`,
+			"layer: lib: at least one package pattern must be specified",
+		},
+		{
+			`<godepcop><layer name="lib" packages="a/... a/*b**"/></godepcop>`,
+			`layer: lib: "a/*b**": "**" must be a whole path element`,
+		},
+		{
+			`<godepcop><layer name="lib" packages="a"/><layer name="lib" packages="b"/></godepcop>`,
+			"layer: lib: duplicate layer name",
+		},
 	}
 	for _, test := range tests {
 		cfg, err := parseConfig([]byte(test.Data))
@@ -192,3 +268,50 @@ func TestParseConfigError(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigIterModuleRoot(t *testing.T) {
+	// Create a module with config files above and below the module root.
+	dir, err := ioutil.TempDir("", "godepcop")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	modDir := filepath.Join(dir, "mod")
+	pkgDir := filepath.Join(modDir, "a", "b")
+	if err := os.MkdirAll(pkgDir, os.ModePerm); err != nil {
+		t.Fatalf("MkdirAll(%q) failed: %v", pkgDir, err)
+	}
+	for _, d := range []string{dir, modDir, pkgDir} {
+		path := filepath.Join(d, configFileName)
+		if err := ioutil.WriteFile(path, []byte(testConfigXML), os.ModePerm); err != nil {
+			t.Fatalf("WriteFile(%q) failed: %v", path, err)
+		}
+	}
+	// The iterator must stop at the module root, even though the import path
+	// has more elements than the directory depth within the module.
+	p := &build.Package{ImportPath: "example.com/x/y/mod/a/b", Dir: pkgDir}
+	pkgMu.Lock()
+	moduleDirs[p.ImportPath] = modDir
+	pkgMu.Unlock()
+	defer func() {
+		pkgMu.Lock()
+		delete(moduleDirs, p.ImportPath)
+		pkgMu.Unlock()
+	}()
+	var got []string
+	it := newConfigIter(p)
+	for it.Advance() {
+		got = append(got, it.Value().Path)
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("iteration failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(pkgDir, configFileName),
+		filepath.Join(modDir, "a", configFileName),
+		filepath.Join(modDir, configFileName),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"sort"
+	"strings"
+)
+
+// groupEdge is an import of group Dst by group Src, along with the package
+// imports that cause it.
+type groupEdge struct {
+	Src, Dst string
+	Imports  []depEdge
+}
+
+// groupCycle is a strongly-connected component of the group graph, containing
+// more than one group.
+type groupCycle struct {
+	Groups []string
+	Edges  []groupEdge
+}
+
+// groupOf returns the group of the package with the given import path, which is
+// the prefix of the path containing at most depth elements.  If depth isn't
+// positive, each package is in its own group.
+func groupOf(path string, depth int) string {
+	elems := strings.Split(path, "/")
+	if depth <= 0 || len(elems) <= depth {
+		return path
+	}
+	return strings.Join(elems[:depth], "/")
+}
+
+// findCycles collapses pkgs and their dependencies into groups of the given
+// depth, and returns the cycles between groups.
+func findCycles(pkgs []*build.Package, opts depOpts, depth int) ([]groupCycle, error) {
+	// Gather the packages and their dependencies.
+	all := make(map[string]*build.Package)
+	for _, pkg := range pkgs {
+		all[pkg.ImportPath] = pkg
+		if err := opts.Deps(pkg, all); err != nil {
+			return nil, err
+		}
+	}
+	roots := packageSet(pkgs)
+	// Build the group graph, remembering the package imports behind each edge.
+	edges := make(map[string]map[string][]depEdge)
+	for _, pkg := range sortPackages(all) {
+		src := groupOf(pkg.ImportPath, depth)
+		if edges[src] == nil {
+			edges[src] = make(map[string][]depEdge)
+		}
+		paths := pkg.Imports
+		if roots[pkg.ImportPath] {
+			paths = opts.Paths(pkg)
+		}
+		for _, path := range paths {
+			if all[path] == nil {
+				continue
+			}
+			if dst := groupOf(path, depth); dst != src {
+				edges[src][dst] = append(edges[src][dst], depEdge{pkg.ImportPath, path})
+			}
+		}
+	}
+	// Find the strongly-connected components with more than one group.
+	var cycles []groupCycle
+	for _, scc := range stronglyConnected(edges) {
+		if len(scc) < 2 {
+			continue
+		}
+		sort.Strings(scc)
+		members := make(map[string]bool)
+		for _, group := range scc {
+			members[group] = true
+		}
+		c := groupCycle{Groups: scc}
+		for _, src := range scc {
+			var dsts []string
+			for dst := range edges[src] {
+				if members[dst] {
+					dsts = append(dsts, dst)
+				}
+			}
+			sort.Strings(dsts)
+			for _, dst := range dsts {
+				c.Edges = append(c.Edges, groupEdge{src, dst, edges[src][dst]})
+			}
+		}
+		cycles = append(cycles, c)
+	}
+	sort.Sort(cycleSorter(cycles))
+	return cycles, nil
+}
+
+type cycleSorter []groupCycle
+
+func (s cycleSorter) Len() int           { return len(s) }
+func (s cycleSorter) Less(i, j int) bool { return s[i].Groups[0] < s[j].Groups[0] }
+func (s cycleSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// stronglyConnected returns the strongly-connected components of the graph
+// described by edges, using Tarjan's algorithm.
+func stronglyConnected(edges map[string]map[string][]depEdge) [][]string {
+	var (
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		sccs    [][]string
+		visit   func(v string)
+	)
+	visit = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for w := range edges[v] {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+		if lowlink[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+	var vertices []string
+	for v := range edges {
+		vertices = append(vertices, v)
+	}
+	sort.Strings(vertices)
+	for _, v := range vertices {
+		if _, ok := index[v]; !ok {
+			visit(v)
+		}
+	}
+	return sccs
+}
+
+// printCycles prints each cycle to w, followed by the group edges within the
+// cycle and the package imports behind each of them.
+func printCycles(w io.Writer, cycles []groupCycle) {
+	for _, c := range cycles {
+		fmt.Fprintf(w, "cycle: %s\n", strings.Join(c.Groups, " "))
+		for _, edge := range c.Edges {
+			fmt.Fprintf(w, "  %s -> %s\n", edge.Src, edge.Dst)
+			for _, imp := range edge.Imports {
+				fmt.Fprintf(w, "    %s -> %s\n", imp.Src, imp.Dst)
+			}
+		}
+	}
+}
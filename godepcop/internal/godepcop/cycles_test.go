@@ -0,0 +1,87 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"go/build"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGroupOf(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		group string
+	}{
+		{"a/b/c", 0, "a/b/c"},
+		{"a/b/c", 1, "a"},
+		{"a/b/c", 2, "a/b"},
+		{"a/b/c", 3, "a/b/c"},
+		{"a/b/c", 4, "a/b/c"},
+	}
+	for _, test := range tests {
+		if got, want := groupOf(test.path, test.depth), test.group; got != want {
+			t.Errorf("groupOf(%q, %d) got %q, want %q", test.path, test.depth, got, want)
+		}
+	}
+}
+
+func TestStronglyConnected(t *testing.T) {
+	edges := map[string]map[string][]depEdge{
+		"a": {"b": nil},
+		"b": {"c": nil},
+		"c": {"a": nil, "d": nil},
+		"d": {"e": nil},
+		"e": {"d": nil},
+		"f": {"a": nil},
+	}
+	var got [][]string
+	for _, scc := range stronglyConnected(edges) {
+		if len(scc) > 1 {
+			sortedSCC := append([]string(nil), scc...)
+			sort.Strings(sortedSCC)
+			got = append(got, sortedSCC)
+		}
+	}
+	want := [][]string{{"d", "e"}, {"a", "b", "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	pkg, err := importPackage(v + "test-layers/lib/bad")
+	if err != nil {
+		t.Fatalf("importPackage failed: %v", err)
+	}
+	// With a group depth of 7, lib and cmd import each other.
+	cycles, err := findCycles([]*build.Package{pkg}, depOpts{}, 7)
+	if err != nil {
+		t.Fatalf("findCycles failed: %v", err)
+	}
+	var buf bytes.Buffer
+	printCycles(&buf, cycles)
+	want := `cycle: ` + v + `test-layers/cmd ` + v + `test-layers/lib
+  ` + v + `test-layers/cmd -> ` + v + `test-layers/lib
+    ` + v + `test-layers/cmd -> ` + v + `test-layers/lib
+  ` + v + `test-layers/lib -> ` + v + `test-layers/cmd
+    ` + v + `test-layers/lib/bad -> ` + v + `test-layers/cmd
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// With a group depth of 6, everything is in the same group.
+	cycles, err = findCycles([]*build.Package{pkg}, depOpts{}, 6)
+	if err != nil {
+		t.Fatalf("findCycles failed: %v", err)
+	}
+	if len(cycles) != 0 {
+		t.Errorf("got %v, want no cycles", cycles)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// depEdge is an import of Dst by Src.
+type depEdge struct {
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+}
+
+// depGraph holds a set of packages and the imports between them.
+type depGraph struct {
+	Packages map[string]bool
+	Edges    map[depEdge]bool
+}
+
+// graphDiff describes the changes between two dependency graphs.
+type graphDiff struct {
+	AddedPackages   []string  `json:"addedPackages"`
+	RemovedPackages []string  `json:"removedPackages"`
+	AddedEdges      []depEdge `json:"addedEdges"`
+	RemovedEdges    []depEdge `json:"removedEdges"`
+}
+
+// loadDepGraph runs "go list" in dir, with env added to the environment, over
+// the given patterns, and returns the graph of the matching packages and all of
+// their transitive dependencies.  If test is true, imports from test files of
+// the matching packages are included.
+func loadDepGraph(dir string, env []string, patterns []string, goroot, test bool) (*depGraph, error) {
+	args := []string{"-deps"}
+	if test {
+		args = append(args, "-test")
+	}
+	listed, err := runGoList(dir, env, append(args, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatched(patterns, listed); err != nil {
+		return nil, err
+	}
+	g := &depGraph{Packages: make(map[string]bool), Edges: make(map[depEdge]bool)}
+	isGoroot := make(map[string]bool)
+	for _, lp := range listed {
+		if lp.Goroot {
+			isGoroot[lp.ImportPath] = true
+		}
+	}
+	for _, lp := range listed {
+		src := lp.ImportPath
+		if lp.ForTest == "" && lp.Name == "main" && strings.HasSuffix(src, ".test") {
+			// Skip the generated test main package.
+			continue
+		}
+		// Fold the test variants "p [p.test]" and "p_test [p.test]" into p.
+		// Dependencies that are recompiled for the test of p, like
+		// "d [p.test]", are folded into d rather than p, since their imports
+		// are still imports of d.
+		if index := strings.Index(src, " ["); index != -1 {
+			src = src[:index]
+		}
+		if lp.ForTest != "" && src == lp.ForTest+"_test" {
+			src = lp.ForTest
+		}
+		if !goroot && isGoroot[src] {
+			continue
+		}
+		g.Packages[src] = true
+		for _, dst := range lp.Imports {
+			// Strip the test variant suffix, e.g. " [p.test]".
+			if index := strings.Index(dst, " ["); index != -1 {
+				dst = dst[:index]
+			}
+			if dst == src || !goroot && (isGoroot[dst] || dst == "C") {
+				continue
+			}
+			g.Edges[depEdge{src, dst}] = true
+		}
+	}
+	return g, nil
+}
+
+// diffDepGraphs returns the packages and edges that were added and removed
+// between the before and after graphs.
+func diffDepGraphs(before, after *depGraph) graphDiff {
+	d := graphDiff{
+		AddedPackages:   []string{},
+		RemovedPackages: []string{},
+		AddedEdges:      []depEdge{},
+		RemovedEdges:    []depEdge{},
+	}
+	for pkg := range after.Packages {
+		if !before.Packages[pkg] {
+			d.AddedPackages = append(d.AddedPackages, pkg)
+		}
+	}
+	for pkg := range before.Packages {
+		if !after.Packages[pkg] {
+			d.RemovedPackages = append(d.RemovedPackages, pkg)
+		}
+	}
+	for edge := range after.Edges {
+		if !before.Edges[edge] {
+			d.AddedEdges = append(d.AddedEdges, edge)
+		}
+	}
+	for edge := range before.Edges {
+		if !after.Edges[edge] {
+			d.RemovedEdges = append(d.RemovedEdges, edge)
+		}
+	}
+	sort.Strings(d.AddedPackages)
+	sort.Strings(d.RemovedPackages)
+	sort.Sort(edgeSorter(d.AddedEdges))
+	sort.Sort(edgeSorter(d.RemovedEdges))
+	return d
+}
+
+// Print writes the diff to w in the given format.
+func (d graphDiff) Print(w io.Writer, format string) error {
+	switch format {
+	case formatText:
+		for _, pkg := range d.AddedPackages {
+			fmt.Fprintf(w, "+ %s\n", pkg)
+		}
+		for _, pkg := range d.RemovedPackages {
+			fmt.Fprintf(w, "- %s\n", pkg)
+		}
+		for _, edge := range d.AddedEdges {
+			fmt.Fprintf(w, "+ %s -> %s\n", edge.Src, edge.Dst)
+		}
+		for _, edge := range d.RemovedEdges {
+			fmt.Fprintf(w, "- %s -> %s\n", edge.Src, edge.Dst)
+		}
+		return nil
+	case formatJSON:
+		return writeJSON(w, d)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+type edgeSorter []depEdge
+
+func (s edgeSorter) Len() int { return len(s) }
+func (s edgeSorter) Less(i, j int) bool {
+	if s[i].Src != s[j].Src {
+		return s[i].Src < s[j].Src
+	}
+	return s[i].Dst < s[j].Dst
+}
+func (s edgeSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// loadDepGraphAtRev checks out the given git revision of the repository
+// containing the current directory into a temporary worktree, and loads the
+// dependency graph from the corresponding directory of the worktree.
+func loadDepGraphAtRev(rev string, patterns []string, goroot, test bool) (_ *depGraph, e error) {
+	prefix, err := runGit("", "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := ioutil.TempDir("", "godepcop-diff")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	worktree := filepath.Join(tmpDir, "src")
+	var env []string
+	if root, gopath, err := gopathRepoRoot(prefix); err != nil {
+		return nil, err
+	} else if gopath {
+		// In GOPATH mode, the go tool finds packages by their import paths
+		// rather than their directories, so the worktree is checked out at
+		// the import path of the repository, in a GOPATH entry of its own
+		// that takes precedence over the others.
+		worktree = filepath.Join(tmpDir, "src", filepath.FromSlash(root))
+		env = []string{"GOPATH=" + tmpDir + string(filepath.ListSeparator) + build.Default.GOPATH}
+	}
+	if _, err := runGit("", "worktree", "add", "--detach", worktree, rev); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, err := runGit("", "worktree", "remove", "--force", worktree); err != nil && e == nil {
+			e = err
+		}
+	}()
+	return loadDepGraph(filepath.Join(worktree, prefix), env, patterns, goroot, test)
+}
+
+// gopathRepoRoot returns the import path of the root of the repository
+// containing the current directory, which is prefix relative to the root, and
+// true, if the go tool is in GOPATH mode.  It returns false in module mode, and
+// an error if the current directory isn't in GOPATH in GOPATH mode.
+func gopathRepoRoot(prefix string) (string, bool, error) {
+	listed, err := runGoList("", nil, ".")
+	if err != nil {
+		return "", false, err
+	}
+	if len(listed) != 1 {
+		return "", false, fmt.Errorf("go list . returned %d packages, want 1", len(listed))
+	}
+	if lp := listed[0]; lp.Module != nil || lp.Dir == "" {
+		return "", false, nil
+	}
+	dir, path := listed[0].Dir, listed[0].ImportPath
+	if strings.HasPrefix(path, "_/") || !strings.HasSuffix("/"+path+"/", "/"+prefix) {
+		return "", false, fmt.Errorf("%s is not in GOPATH %s", dir, build.Default.GOPATH)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(path+"/", prefix), "/"), true, nil
+}
+
+// runGit runs git with the given args in dir, or the current directory if dir
+// is empty, and returns its trimmed standard output.
+func runGit(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
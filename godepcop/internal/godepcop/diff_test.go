@@ -0,0 +1,93 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLoadDepGraph(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	g, err := loadDepGraph("", nil, []string{v + "test-b"}, false, false)
+	if err != nil {
+		t.Fatalf("loadDepGraph failed: %v", err)
+	}
+	want := &depGraph{
+		Packages: map[string]bool{v + "test-a": true, v + "test-b": true, v + "test-c": true},
+		Edges: map[depEdge]bool{
+			{v + "test-b", v + "test-c"}: true,
+			{v + "test-c", v + "test-a"}: true,
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("got %v, want %v", g, want)
+	}
+}
+
+func TestLoadDepGraphTest(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	// q has internal tests, and its external test imports d, which imports q,
+	// so d is recompiled for the test.  Its imports must not be reported as
+	// imports of q.
+	g, err := loadDepGraph("", nil, []string{v + "test-recompile/q"}, false, true)
+	if err != nil {
+		t.Fatalf("loadDepGraph failed: %v", err)
+	}
+	q, d := v+"test-recompile/q", v+"test-recompile/d"
+	want := &depGraph{
+		Packages: map[string]bool{v + "test-a": true, q: true, d: true},
+		Edges: map[depEdge]bool{
+			{q, d}:            true,
+			{d, q}:            true,
+			{d, v + "test-a"}: true,
+		},
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("got %v, want %v", g, want)
+	}
+}
+
+func TestGopathRepoRoot(t *testing.T) {
+	root, gopath, err := gopathRepoRoot("godepcop/internal/godepcop/")
+	if err != nil {
+		t.Fatalf("gopathRepoRoot failed: %v", err)
+	}
+	if got, want := root, "v.io/x/devtools"; !gopath || got != want {
+		t.Errorf("got %q, %v, want %q, true", got, gopath, want)
+	}
+	if _, _, err := gopathRepoRoot("other/"); err == nil {
+		t.Errorf("gopathRepoRoot with a mismatched prefix succeeded, want an error")
+	}
+}
+
+func TestDiffDepGraphs(t *testing.T) {
+	before := &depGraph{
+		Packages: map[string]bool{"a": true, "b": true, "c": true},
+		Edges:    map[depEdge]bool{{"a", "b"}: true, {"b", "c"}: true},
+	}
+	after := &depGraph{
+		Packages: map[string]bool{"a": true, "b": true, "d": true},
+		Edges:    map[depEdge]bool{{"a", "b"}: true, {"a", "d"}: true, {"b", "d"}: true},
+	}
+	d := diffDepGraphs(before, after)
+	want := graphDiff{
+		AddedPackages:   []string{"d"},
+		RemovedPackages: []string{"c"},
+		AddedEdges:      []depEdge{{"a", "d"}, {"b", "d"}},
+		RemovedEdges:    []depEdge{{"b", "c"}},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("got %v, want %v", d, want)
+	}
+	var buf bytes.Buffer
+	if err := d.Print(&buf, formatText); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	if got, want := buf.String(), "+ d\n- c\n+ a -> d\n+ b -> d\n- b -> c\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
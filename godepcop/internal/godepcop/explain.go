@@ -0,0 +1,128 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"sort"
+	"strings"
+)
+
+// importChains returns every shortest chain of imports from src to the package
+// with import path dst, using opts to decide which imports are followed.  Each
+// chain starts with src and ends with dst.  Returns nil if dst isn't imported.
+// The DirectOnly option is ignored.
+func importChains(src *build.Package, dst string, opts depOpts) ([][]*build.Package, error) {
+	// Breadth-first search from src, remembering all predecessors of each
+	// package that lie on a shortest path.
+	dist := map[string]int{src.ImportPath: 0}
+	preds := make(map[string][]*build.Package)
+	var target *build.Package
+	level := []*build.Package{src}
+	for depth := 1; len(level) > 0 && target == nil; depth++ {
+		var next []*build.Package
+		for _, pkg := range level {
+			paths := pkg.Imports
+			if pkg == src {
+				paths = opts.Paths(pkg)
+			}
+			for _, path := range paths {
+				dep, err := importPackage(path)
+				if err != nil {
+					return nil, err
+				}
+				if !opts.IncludeGoroot && dep.Goroot {
+					continue
+				}
+				if d, ok := dist[path]; !ok {
+					dist[path] = depth
+					next = append(next, dep)
+					if path == dst {
+						target = dep
+					}
+				} else if d != depth {
+					continue
+				}
+				preds[path] = append(preds[path], pkg)
+			}
+		}
+		level = next
+	}
+	if target == nil {
+		return nil, nil
+	}
+	// Walk backwards from the target to collect the chains.
+	var chains [][]*build.Package
+	var walk func(pkg *build.Package, suffix []*build.Package)
+	walk = func(pkg *build.Package, suffix []*build.Package) {
+		chain := append([]*build.Package{pkg}, suffix...)
+		if pkg == src {
+			chains = append(chains, chain)
+			return
+		}
+		for _, pred := range preds[pkg.ImportPath] {
+			walk(pred, chain)
+		}
+	}
+	walk(target, nil)
+	sort.Sort(chainSorter(chains))
+	return chains, nil
+}
+
+func chainString(chain []*build.Package) string {
+	var paths []string
+	for _, pkg := range chain {
+		paths = append(paths, pkg.ImportPath)
+	}
+	return strings.Join(paths, " -> ")
+}
+
+type chainSorter [][]*build.Package
+
+func (s chainSorter) Len() int           { return len(s) }
+func (s chainSorter) Less(i, j int) bool { return chainString(s[i]) < chainString(s[j]) }
+func (s chainSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// explainDep writes an explanation of why pkg may or may not import dep to w.
+// For each check mode, the shortest import chains from pkg to dep are printed,
+// followed by a trace of the rules that were evaluated.
+func explainDep(w io.Writer, pkg, dep *build.Package) error {
+	direct := depOpts{DirectOnly: true, IncludeGoroot: true, IncludeTest: true, IncludeXTest: true}
+	for _, path := range direct.Paths(pkg) {
+		if path != dep.ImportPath {
+			continue
+		}
+		if !verifyGo15InternalRule(pkg.ImportPath, dep.ImportPath) {
+			fmt.Fprintf(w, "%q directly imports %q, which %v\n", pkg.ImportPath, dep.ImportPath, errGo15Internal)
+		}
+		if !dep.Goroot {
+			fmt.Fprintf(w, "importers: rules of %q:\n", dep.ImportPath)
+			if _, err := traceCheckImporter(w, pkg, dep); err != nil {
+				return err
+			}
+		}
+	}
+	for _, mode := range allModes {
+		chains, err := importChains(pkg, dep.ImportPath, mode.DepOpts())
+		if err != nil {
+			return err
+		}
+		if len(chains) == 0 {
+			fmt.Fprintf(w, "%s: %q does not import %q\n", mode, pkg.ImportPath, dep.ImportPath)
+			continue
+		}
+		fmt.Fprintf(w, "%s: shortest import chains:\n", mode)
+		for _, chain := range chains {
+			fmt.Fprintf(w, "  %s\n", chainString(chain))
+		}
+		fmt.Fprintf(w, "%s: rules:\n", mode)
+		if _, err := traceCheckDep(w, pkg, dep, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
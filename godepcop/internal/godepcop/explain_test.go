@@ -0,0 +1,101 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportChains(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	tests := []struct {
+		src, dst string
+		chains   []string
+	}{
+		{v + "test-a", v + "test-b", nil},
+		{v + "test-a", "fmt", []string{v + "test-a -> fmt"}},
+		{v + "test-b", v + "test-a", []string{v + "test-b -> " + v + "test-c -> " + v + "test-a"}},
+		{v + "test-diamond", v + "test-a", []string{
+			v + "test-diamond -> " + v + "test-c -> " + v + "test-a",
+			v + "test-diamond -> " + v + "test-diamond/child -> " + v + "test-a",
+		}},
+	}
+	for _, test := range tests {
+		pkg, err := importPackage(test.src)
+		if err != nil {
+			t.Errorf("importPackage(%q) failed: %v", test.src, err)
+			continue
+		}
+		chains, err := importChains(pkg, test.dst, depOpts{IncludeGoroot: true})
+		if err != nil {
+			t.Errorf("importChains(%q, %q) failed: %v", test.src, test.dst, err)
+			continue
+		}
+		var got []string
+		for _, chain := range chains {
+			got = append(got, chainString(chain))
+		}
+		if want := test.chains; !reflect.DeepEqual(got, want) {
+			t.Errorf("importChains(%q, %q) got %q, want %q", test.src, test.dst, got, want)
+		}
+	}
+}
+
+func TestExplainDep(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	tests := []struct {
+		src, dst string
+		want     []string
+	}{
+		{v + "test-b", "fmt", []string{
+			"pkg: shortest import chains:\n  " + v + "test-b -> fmt\n",
+			"    deny=\"fmt\": rejected\n",
+			"  rejected by deny=\"fmt\"",
+		}},
+		{v + "test-c/child", "fmt", []string{
+			"    allow=\"fmt\": approved\n",
+			"  approved by allow=\"fmt\"",
+		}},
+		{v + "test-internal-fail", v + "test-internal/internal", []string{
+			"which violates Go 1.5 internal package rule\n",
+		}},
+		{v + "test-visibility-fail", v + "test-visibility", []string{
+			"importers: rules of \"" + v + "test-visibility\":\n",
+			"    allow=\"" + v + "test-visibility/...\": undecided\n",
+			"  rejected by deny=\"...\"",
+		}},
+		{v + "test-layers/lib/bad", v + "test-layers/cmd", []string{
+			"\"" + v + "test-layers/lib/bad\" in layer \"lib\", \"" + v + "test-layers/cmd\" in layer \"cmd\"\n",
+		}},
+		{v + "test-a", v + "test-b", []string{
+			"pkg: \"" + v + "test-a\" does not import \"" + v + "test-b\"\n",
+		}},
+	}
+	for _, test := range tests {
+		src, err := importPackage(test.src)
+		if err != nil {
+			t.Errorf("importPackage(%q) failed: %v", test.src, err)
+			continue
+		}
+		dst, err := importPackage(test.dst)
+		if err != nil {
+			t.Errorf("importPackage(%q) failed: %v", test.dst, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := explainDep(&buf, src, dst); err != nil {
+			t.Errorf("explainDep(%q, %q) failed: %v", test.src, test.dst, err)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("explainDep(%q, %q) got %v, want it to contain %q", test.src, test.dst, buf.String(), want)
+			}
+		}
+	}
+}
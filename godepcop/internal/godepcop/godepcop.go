@@ -0,0 +1,424 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"regexp"
+	"strings"
+)
+
+type result int
+
+const (
+	resultUndecided result = iota
+	resultApproved
+	resultRejected
+)
+
+func (r result) String() string {
+	return []string{"undecided", "approved", "rejected"}[int(r)]
+}
+
+type violation struct {
+	Src, Dst  *build.Package
+	Err       error
+	Mode      checkMode        // The mode in which the violation was found.
+	Rule      *rule            // The deciding rule; nil for the internal package rule and layers.
+	Config    string           // Path of the config file containing Rule or the layers.
+	Chain     []*build.Package // A shortest import chain from Src to Dst.
+	Platforms []string         // The platforms the violation occurs on, if checked per platform.
+}
+
+func enforceRule(r rule, pkg *build.Package) (result, error) {
+	switch matched, err := matchPattern(r.Pattern(), pkg); {
+	case err != nil:
+		return resultUndecided, err
+	case !matched:
+		return resultUndecided, nil
+	}
+	for _, except := range r.ExceptPatterns() {
+		switch matched, err := matchPattern(except, pkg); {
+		case err != nil:
+			return resultUndecided, err
+		case matched:
+			return resultUndecided, nil
+		}
+	}
+	if r.IsDeny() {
+		return resultRejected, nil
+	}
+	return resultApproved, nil
+}
+
+// matchPattern returns true iff the import path of pkg matches the pattern.
+// The special pattern "..." matches all packages except $GOROOT packages.
+func matchPattern(pattern string, pkg *build.Package) (bool, error) {
+	if pattern == "..." {
+		return !pkg.Goroot, nil
+	}
+	re, err := patternRegexp(pattern)
+	if err != nil {
+		return false, err
+	}
+	return regexp.MatchString("^"+re+"$", pkg.ImportPath)
+}
+
+// patternRegexp returns the regular expression matching the import paths that
+// match the pattern.  A "*" within a path element matches any part of a single
+// element, e.g. "a/*/internal" or "a/*_test".  A "**" path element matches
+// zero or more elements, e.g. "a/**/testutil" or "**/testutil".  A trailing
+// "..." element matches the preceding path and all paths below it, e.g.
+// "a/...".
+func patternRegexp(pattern string) (string, error) {
+	if pattern == "..." || pattern == "**" {
+		return ".*", nil
+	}
+	var re string
+	elems := strings.Split(pattern, "/")
+	for i, elem := range elems {
+		// The separator before the element; a leading "**" already includes it.
+		sep := "/"
+		if i == 0 || elems[i-1] == "**" && i == 1 {
+			sep = ""
+		}
+		switch {
+		case elem == "":
+			return "", fmt.Errorf("%q: empty path element", pattern)
+		case elem == "...":
+			switch {
+			case i != len(elems)-1:
+				return "", fmt.Errorf(`%q: "..." must be the last path element`, pattern)
+			case elems[i-1] == "**":
+				return "", fmt.Errorf(`%q: "..." may not follow "**"`, pattern)
+			}
+			re += "(/.*)?"
+		case elem == "**":
+			switch {
+			case i > 0 && elems[i-1] == "**":
+				return "", fmt.Errorf(`%q: "**" may not follow "**"`, pattern)
+			case i == 0:
+				re += "([^/]+/)*"
+			default:
+				re += "(/[^/]+)*"
+			}
+		case strings.Contains(elem, "..."):
+			return "", fmt.Errorf(`%q: "..." must be a whole path element`, pattern)
+		case strings.Contains(elem, "**"):
+			return "", fmt.Errorf(`%q: "**" must be a whole path element`, pattern)
+		default:
+			var parts []string
+			for _, part := range strings.Split(elem, "*") {
+				parts = append(parts, regexp.QuoteMeta(part))
+			}
+			re += sep + strings.Join(parts, "[^/]*")
+		}
+	}
+	return re, nil
+}
+
+// verifyGo15InternalRule implements support for the internal package rule,
+// which is supposed to be enabled for GOPATH packages in Go 1.5.  This logic
+// can be removed after Go 1.5 is released.
+//
+// https://docs.google.com/document/d/1e8kOo3r51b2BWtTs_1uADIA5djfXhPT36s6eHVRIvaU
+func verifyGo15InternalRule(src, dst string) bool {
+	// The rule is that package "a/b/c/internal/d/e/f" can only be imported by
+	// code rooted at "a/b/c".  The doc above isn't clear, but if there are
+	// multiple occurrences of "internal", we apply the rule to the last (deepest)
+	// occurrence.
+	//
+	// The only tricky part is to ensure path components are matched correctly.
+	// E.g. when looking for the last path component that is "internal", we don't
+	// want to match "Xinternal" or "internalX".
+	const internal = "/internal"
+	var root string
+	if strings.HasSuffix(dst, internal) {
+		root = dst[:len(dst)-len(internal)]
+	} else if index := strings.LastIndex(dst, internal+"/"); index != -1 {
+		root = dst[:index]
+	}
+	return root == "" || src == root || strings.HasPrefix(src, root+"/")
+}
+
+var errGo15Internal = errors.New("violates Go 1.5 internal package rule")
+
+func checkDep(pkg, dep *build.Package, mode checkMode) (*violation, error) {
+	return traceCheckDep(nil, pkg, dep, mode)
+}
+
+// rules returns the ordered rules of c that apply in the given mode.  The
+// configs are cached and shared by the workers of checkDeps, so the rules are
+// copied into a new slice rather than appended to the config's own.
+func (c *config) rules(mode checkMode) []rule {
+	switch mode {
+	case modeTest:
+		return append(append([]rule(nil), c.TestRules...), c.PkgRules...)
+	case modeXTest:
+		return append(append(append([]rule(nil), c.XTestRules...), c.TestRules...), c.PkgRules...)
+	}
+	return c.PkgRules
+}
+
+// traceCheckDep implements checkDep.  If trace is non-nil, each config file
+// visited, each rule tried and the final decision are written to trace.
+func traceCheckDep(trace io.Writer, pkg, dep *build.Package, mode checkMode) (*violation, error) {
+	// Collect the ordered rules from each config for the given mode.
+	rulesFor := func(cfg *config) []rule { return cfg.rules(mode) }
+	switch r, cfg, err := traceRules(trace, newConfigIter(pkg), mode.String(), rulesFor, dep); {
+	case err != nil:
+		return nil, err
+	case r != nil && r.IsDeny():
+		err := fmt.Errorf(`violates %s deny rule %q in %s`, mode, r.Pattern(), cfg)
+		return &violation{Src: pkg, Dst: dep, Err: err, Mode: mode, Rule: r, Config: cfg}, nil
+	}
+	// The layer order is enforced independently of the rules; an allow rule
+	// doesn't permit an import from a lower to a higher layer.
+	return traceCheckLayers(trace, pkg, dep, mode)
+}
+
+// traceCheckLayers checks that dep isn't in a higher layer than pkg, using the
+// layers declared in the nearest .godepcop file of pkg that declares any.  If
+// trace is non-nil, the layer lookup is written to trace.
+func traceCheckLayers(trace io.Writer, pkg, dep *build.Package, mode checkMode) (*violation, error) {
+	it := newConfigIter(pkg)
+	for it.Advance() {
+		cfg := it.Value()
+		if len(cfg.Layers) == 0 {
+			continue
+		}
+		src, err := findLayer(cfg.Layers, pkg)
+		if err != nil {
+			return nil, err
+		}
+		dst, err := findLayer(cfg.Layers, dep)
+		if err != nil {
+			return nil, err
+		}
+		if trace != nil {
+			fmt.Fprintf(trace, "  %s: %q in layer %s, %q in layer %s\n", cfg.Path, pkg.ImportPath, layerName(cfg.Layers, src), dep.ImportPath, layerName(cfg.Layers, dst))
+		}
+		if src >= 0 && dst > src {
+			err := fmt.Errorf(`violates %s layer order in %s: layer %q may not import higher layer %q`, mode, cfg.Path, cfg.Layers[src].Name, cfg.Layers[dst].Name)
+			return &violation{Src: pkg, Dst: dep, Err: err, Mode: mode, Config: cfg.Path}, nil
+		}
+		return nil, nil
+	}
+	return nil, it.Err()
+}
+
+// findLayer returns the index of the first layer with a pattern matching pkg,
+// or -1 if pkg isn't in any layer.
+func findLayer(layers []layer, pkg *build.Package) (int, error) {
+	for i, l := range layers {
+		for _, pattern := range l.Patterns() {
+			result, err := enforceRule(rule{Allow: &pattern}, pkg)
+			if err != nil {
+				return -1, err
+			}
+			if result == resultApproved {
+				return i, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+func layerName(layers []layer, index int) string {
+	if index < 0 {
+		return "<none>"
+	}
+	return fmt.Sprintf("%q", layers[index].Name)
+}
+
+// checkImporter checks whether pkg is allowed to directly import dep, according
+// to the importers rules of dep.
+func checkImporter(pkg, dep *build.Package) (*violation, error) {
+	return traceCheckImporter(nil, pkg, dep)
+}
+
+// traceCheckImporter implements checkImporter.  If trace is non-nil, the rule
+// evaluation is written to trace.
+func traceCheckImporter(trace io.Writer, pkg, dep *build.Package) (*violation, error) {
+	if dep.Goroot {
+		return nil, nil
+	}
+	rulesFor := func(cfg *config) []rule { return cfg.ImporterRules }
+	switch r, cfg, err := traceRules(trace, newConfigIter(dep), "importers", rulesFor, pkg); {
+	case err != nil:
+		return nil, err
+	case r != nil && r.IsDeny():
+		err := fmt.Errorf(`violates importers deny rule %q in %s`, r.Pattern(), cfg)
+		v := &violation{Src: pkg, Dst: dep, Err: err, Mode: directImportMode(pkg, dep.ImportPath), Rule: r, Config: cfg}
+		v.Chain = []*build.Package{pkg, dep}
+		return v, nil
+	}
+	return nil, nil
+}
+
+// traceRules enforces the rules returned by rulesFor for each config visited by
+// it against target, and returns the first rule that approves or rejects the
+// target along with the path of its config file.  Returns a nil rule if no rule
+// matches, which is treated as approval.  If trace is non-nil, each config file
+// visited, each rule tried and the final decision are written to trace.
+func traceRules(trace io.Writer, it *configIter, group string, rulesFor func(*config) []rule, target *build.Package) (*rule, string, error) {
+	tracef := func(format string, args ...interface{}) {
+		if trace != nil {
+			fmt.Fprintf(trace, format, args...)
+		}
+	}
+	for it.Advance() {
+		cfg := it.Value()
+		rules := rulesFor(cfg)
+		if len(rules) == 0 {
+			tracef("  %s: no %s rules\n", cfg.Path, group)
+			continue
+		}
+		tracef("  %s:\n", cfg.Path)
+		// Enforce each rule in order.
+		for i, rule := range rules {
+			result, err := enforceRule(rule, target)
+			if err != nil {
+				return nil, "", err
+			}
+			tracef("    %v: %v\n", rule, result)
+			switch result {
+			case resultApproved:
+				tracef("  approved by %v in %s\n", rule, cfg.Path)
+				return &rules[i], cfg.Path, nil
+			case resultRejected:
+				tracef("  rejected by %v in %s\n", rule, cfg.Path)
+				return &rules[i], cfg.Path, nil
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, "", err
+	}
+	// All config files have been checked without an approved or rejected result;
+	// treat this as an approved result.  This also handles the case where no
+	// config files have been specified.
+	tracef("  approved by default; no matching rule\n")
+	return nil, "", nil
+}
+
+// checkDirectDep checks the direct import of dep by pkg against the Go 1.5
+// internal package rule, and the importers rules declared by dep.
+func checkDirectDep(pkg, dep *build.Package, mode checkMode) ([]violation, error) {
+	var violations []violation
+	if !verifyGo15InternalRule(pkg.ImportPath, dep.ImportPath) {
+		v := violation{Src: pkg, Dst: dep, Err: errGo15Internal, Mode: mode}
+		v.Chain = []*build.Package{pkg, dep}
+		violations = append(violations, v)
+	}
+	v, err := checkImporter(pkg, dep)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		violations = append(violations, *v)
+	}
+	return violations, nil
+}
+
+func checkDeps(pkg *build.Package) ([]violation, error) {
+	var violations []violation
+	// First check direct dependencies against the Go 1.5 internal package rule,
+	// and the importers rules in the .godepcop files of the dependencies.
+	optsDirect := depOpts{DirectOnly: true, IncludeGoroot: true, IncludeTest: true, IncludeXTest: true}
+	depsDirect := make(map[string]*build.Package)
+	if err := optsDirect.Deps(pkg, depsDirect); err != nil {
+		return nil, err
+	}
+	for _, dep := range sortPackages(depsDirect) {
+		vs, err := checkDirectDep(pkg, dep, directImportMode(pkg, dep.ImportPath))
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, vs...)
+	}
+	// Now check transitive dependencies against the rules in .godepcop files.
+	// Each mode is checked independently, since the .godepcop configuration rules
+	// may be different.
+	for _, mode := range allModes {
+		deps := make(map[string]*build.Package)
+		if err := pkgImports.Deps(pkg, mode.DepOpts(), deps); err != nil {
+			return nil, err
+		}
+		for _, dep := range sortPackages(deps) {
+			v, err := checkDep(pkg, dep, mode)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				chains, err := importChains(pkg, dep.ImportPath, mode.DepOpts())
+				if err != nil {
+					return nil, err
+				}
+				if len(chains) > 0 {
+					v.Chain = chains[0]
+				}
+				violations = append(violations, *v)
+			}
+		}
+	}
+	return violations, nil
+}
+
+type checkMode int
+
+const (
+	modePkg checkMode = iota
+	modeTest
+	modeXTest
+)
+
+var allModes = []checkMode{modePkg, modeTest, modeXTest}
+
+// directImportMode returns the narrowest mode in which pkg directly imports the
+// given path.
+func directImportMode(pkg *build.Package, path string) checkMode {
+	for _, mode := range allModes {
+		opts := mode.DepOpts()
+		opts.DirectOnly = true
+		for _, p := range opts.Paths(pkg) {
+			if p == path {
+				return mode
+			}
+		}
+	}
+	return modeXTest
+}
+
+func (mode checkMode) String() string {
+	return []string{"pkg", "test", "xtest"}[mode]
+}
+
+// parseCheckMode returns the mode with the given name.
+func parseCheckMode(name string) (checkMode, error) {
+	for _, mode := range allModes {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q", name)
+}
+
+// DepOpts returns the options for computing the transitive dependencies that
+// are checked in the given mode.
+func (mode checkMode) DepOpts() depOpts {
+	opts := depOpts{IncludeGoroot: true}
+	switch mode {
+	case modeTest:
+		opts.IncludeTest = true
+	case modeXTest:
+		opts.IncludeTest = true
+		opts.IncludeXTest = true
+	}
+	return opts
+}
@@ -2,16 +2,22 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package godepcop
 
 import (
 	"go/build"
+	"reflect"
+	"sync"
 	"testing"
 )
 
 func allow(expr string) rule         { return rule{Allow: &expr} }
 func deny(expr string) rule          { return rule{Deny: &expr} }
 func pkg(path string) *build.Package { return &build.Package{ImportPath: path} }
+func except(r rule, patterns string) rule {
+	r.Except = &patterns
+	return r
+}
 func pkgGoroot(path string) *build.Package {
 	p := pkg(path)
 	p.Goroot = true
@@ -46,6 +52,41 @@ func TestEnforceRule(t *testing.T) {
 		{allow("foo/..."), pkg("foo/a/b/c"), resultApproved},
 		{allow("foo/..."), pkg("bar"), resultUndecided},
 		{allow("foo/..."), pkg("bar/foo"), resultUndecided},
+
+		{deny("foo/*"), pkg("foo"), resultUndecided},
+		{deny("foo/*"), pkg("foo/a"), resultRejected},
+		{deny("foo/*"), pkg("foo/a/b"), resultUndecided},
+		{deny("foo/*/internal"), pkg("foo/a/internal"), resultRejected},
+		{deny("foo/*/internal"), pkg("foo/a/b/internal"), resultUndecided},
+		{deny("foo/*_test"), pkg("foo/a_test"), resultRejected},
+		{deny("foo/*_test"), pkg("foo/a_testx"), resultUndecided},
+		{deny("foo/a*b"), pkg("foo/ab"), resultRejected},
+		{deny("foo/a*b"), pkg("foo/a/b"), resultUndecided},
+		{deny("foo/a.b"), pkg("foo/axb"), resultUndecided},
+
+		{deny("**"), pkg("foo"), resultRejected},
+		{deny("**"), pkgGoroot("foo"), resultRejected},
+		{deny("**/testutil"), pkg("testutil"), resultRejected},
+		{deny("**/testutil"), pkg("foo/a/testutil"), resultRejected},
+		{deny("**/testutil"), pkg("foo/testutil/a"), resultUndecided},
+		{deny("**/testutil"), pkg("foo/mytestutil"), resultUndecided},
+		{deny("foo/**"), pkg("foo"), resultRejected},
+		{deny("foo/**"), pkg("foo/a/b"), resultRejected},
+		{deny("foo/**"), pkg("foobar"), resultUndecided},
+		{deny("foo/**/internal"), pkg("foo/internal"), resultRejected},
+		{deny("foo/**/internal"), pkg("foo/a/b/internal"), resultRejected},
+		{deny("foo/**/internal"), pkg("foo/a/internal/b"), resultUndecided},
+		{deny("foo/**/internal/..."), pkg("foo/a/internal/b"), resultRejected},
+		{deny("foo/**/*_test"), pkg("foo/a/b_test"), resultRejected},
+
+		{except(deny("foo/..."), "foo/x"), pkg("foo/a"), resultRejected},
+		{except(deny("foo/..."), "foo/x"), pkg("foo/x"), resultUndecided},
+		{except(deny("foo/..."), "foo/x"), pkg("foo/x/a"), resultRejected},
+		{except(deny("foo/..."), "foo/x/... foo/y"), pkg("foo/x/a"), resultUndecided},
+		{except(deny("foo/..."), "foo/x/... foo/y"), pkg("foo/y"), resultUndecided},
+		{except(allow("..."), "**/internal"), pkg("foo/internal"), resultUndecided},
+		{except(allow("..."), "**/internal"), pkg("foo"), resultApproved},
+		{except(allow("..."), "**/internal"), pkgGoroot("foo"), resultUndecided},
 	}
 	for _, test := range tests {
 		result, err := enforceRule(test.rule, test.pkg)
@@ -144,10 +185,17 @@ func TestCheckDeps(t *testing.T) {
 		{"v.io/x/devtools/godepcop/testdata/test-d", false},
 		{"v.io/x/devtools/godepcop/testdata/test-e", false},
 		{"v.io/x/devtools/godepcop/testdata/test-f", false},
+		{"v.io/x/devtools/godepcop/testdata/test-g", false},
 		{"v.io/x/devtools/godepcop/testdata/test-internal", true},
 		{"v.io/x/devtools/godepcop/testdata/test-internal/child", true},
 		{"v.io/x/devtools/godepcop/testdata/test-internal/internal/child", true},
 		{"v.io/x/devtools/godepcop/testdata/test-internal-fail", false},
+		{"v.io/x/devtools/godepcop/testdata/test-visibility", true},
+		{"v.io/x/devtools/godepcop/testdata/test-visibility/child", true},
+		{"v.io/x/devtools/godepcop/testdata/test-visibility-fail", false},
+		{"v.io/x/devtools/godepcop/testdata/test-layers/lib", true},
+		{"v.io/x/devtools/godepcop/testdata/test-layers/lib/bad", false},
+		{"v.io/x/devtools/godepcop/testdata/test-layers/cmd", true},
 		{"v.io/x/devtools/godepcop/testdata/import-C", true},
 		{"v.io/x/devtools/godepcop/testdata/import-unsafe", true},
 	}
@@ -167,3 +215,118 @@ func TestCheckDeps(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigRules(t *testing.T) {
+	// The decoded test and xtest rules have spare capacity, which the pkg and
+	// test rules, respectively, would fit into if they were appended in place.
+	cfg, err := parseConfig([]byte(`<godepcop>
+  <pkg allow="p1"/>
+  <test allow="t1"/><test allow="t2"/><test allow="t3"/>
+  <xtest allow="x1"/><xtest allow="x2"/><xtest allow="x3"/><xtest allow="x4"/><xtest allow="x5"/>
+</godepcop>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode checkMode
+		want []string
+	}{
+		{modePkg, []string{"p1"}},
+		{modeTest, []string{"t1", "t2", "t3", "p1"}},
+		{modeXTest, []string{"x1", "x2", "x3", "x4", "x5", "t1", "t2", "t3", "p1"}},
+	}
+	patterns := func(rules []rule) []string {
+		var s []string
+		for _, r := range rules {
+			s = append(s, r.Pattern())
+		}
+		return s
+	}
+	for _, test := range tests {
+		// The rules of each mode are collected from several goroutines, as the
+		// workers of checkDeps do; run with -race.
+		var wg sync.WaitGroup
+		got := make([][]rule, 4)
+		for i := range got {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				got[i] = cfg.rules(test.mode)
+			}(i)
+		}
+		wg.Wait()
+		for _, rules := range got {
+			if got, want := patterns(rules), test.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("%v got %v, want %v", test.mode, got, want)
+			}
+		}
+		// Changing the rules of one check mustn't change those of another.
+		if test.mode != modePkg {
+			got[0][len(got[0])-1] = allow("changed")
+			if got, want := patterns(got[1]), test.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("%v got %v after changing other rules, want %v", test.mode, got, want)
+			}
+		}
+	}
+}
+
+// TestCheckDepsConcurrently checks a package with test and xtest rules
+// repeatedly from several goroutines, which share the cached config; run it
+// with -race.
+func TestCheckDepsConcurrently(t *testing.T) {
+	p, err := importPackage("v.io/x/devtools/godepcop/testdata/test-g")
+	if err != nil {
+		t.Fatalf("error loading package: %v", err)
+	}
+	want, err := checkDeps(p)
+	if err != nil {
+		t.Fatalf("serial check failed: %v", err)
+	}
+	if len(want) != 1 || want[0].Mode != modeXTest {
+		t.Fatalf("serial check got %v, want one xtest violation", violationStrings(want))
+	}
+	const numWorkers = 8
+	got := make([][]violation, numWorkers)
+	errs := make([]error, numWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20 && errs[i] == nil; j++ {
+				got[i], errs[i] = checkDeps(p)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < numWorkers; i++ {
+		if errs[i] != nil {
+			t.Errorf("worker %d failed: %v", i, errs[i])
+			continue
+		}
+		if got, want := violationStrings(got[i]), violationStrings(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("worker %d got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestCheckPackagesConcurrently(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	var patterns []string
+	for _, path := range []string{"test-a", "test-b", "test-c", "test-c/child", "test-d", "test-e", "test-f", "test-internal-fail", "test-visibility-fail", "test-layers/lib/bad"} {
+		patterns = append(patterns, v+path)
+	}
+	_, want, err := checkPackagesForContext(1, nil, patterns...)
+	if err != nil {
+		t.Fatalf("serial check failed: %v", err)
+	}
+	for _, numWorkers := range []int{2, 8} {
+		_, got, err := checkPackagesForContext(numWorkers, nil, patterns...)
+		if err != nil {
+			t.Fatalf("check with %d workers failed: %v", numWorkers, err)
+		}
+		if got, want := violationStrings(got), violationStrings(want); !reflect.DeepEqual(got, want) {
+			t.Errorf("check with %d workers got %v, want %v", numWorkers, got, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"sort"
+	"strings"
+)
+
+// listNode is a package in the dependency graph printed by list.
+type listNode struct {
+	Path     string `json:"path"`
+	Goroot   bool   `json:"goroot,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// listEdge is an import in the dependency graph printed by list.  The mode
+// is "test" or "xtest" for imports that only occur in the test files of the
+// given packages.
+type listEdge struct {
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+	Mode string `json:"mode"`
+}
+
+// listGraph is the dependency graph of the packages given to list.  Nodes are
+// in the order they are first reached, like the ids assigned by printDot.
+type listGraph struct {
+	Nodes []listNode `json:"nodes"`
+	Edges []listEdge `json:"edges"`
+}
+
+const (
+	categoryGoroot     = "goroot"
+	categoryThirdParty = "third_party"
+	categoryInternal   = "internal"
+)
+
+// packageCategory returns the category of pkg, which determines how it is
+// highlighted: GOROOT, third_party or internal packages.
+func packageCategory(pkg *build.Package) string {
+	path := "/" + pkg.ImportPath + "/"
+	switch {
+	case pkg.Goroot:
+		return categoryGoroot
+	case strings.Contains(path, "/third_party/"):
+		return categoryThirdParty
+	case strings.Contains(path, "/internal/"):
+		return categoryInternal
+	}
+	return ""
+}
+
+// buildListGraph returns the graph of pkgs and their dependencies, traversed
+// in the same way as printDot.
+func buildListGraph(pkgs []*build.Package, opts depOpts) (*listGraph, error) {
+	g := &listGraph{Nodes: []listNode{}, Edges: []listEdge{}}
+	ids := make(map[string]int)
+	addNode := func(pkg *build.Package) {
+		if _, ok := ids[pkg.ImportPath]; ok {
+			return
+		}
+		ids[pkg.ImportPath] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, listNode{
+			Path:     pkg.ImportPath,
+			Goroot:   pkg.Goroot,
+			Module:   modulePaths[pkg.ImportPath],
+			Category: packageCategory(pkg),
+		})
+	}
+	visited := make(map[string]bool)
+	var visit func(pkg *build.Package, paths []string, root bool) error
+	visit = func(pkg *build.Package, paths []string, root bool) error {
+		if visited[pkg.ImportPath] {
+			return nil
+		}
+		visited[pkg.ImportPath] = true
+		addNode(pkg)
+		var deps []*build.Package
+		for _, path := range paths {
+			dep, err := importPackage(path)
+			if err != nil {
+				return err
+			}
+			if !opts.IncludeGoroot && dep.Goroot {
+				continue
+			}
+			addNode(dep)
+			mode := modePkg
+			if root {
+				mode = directImportMode(pkg, path)
+			}
+			g.Edges = append(g.Edges, listEdge{pkg.ImportPath, path, mode.String()})
+			deps = append(deps, dep)
+		}
+		if !opts.DirectOnly {
+			for _, dep := range deps {
+				if err := visit(dep, dep.Imports, false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, pkg := range pkgs {
+		if err := visit(pkg, opts.Paths(pkg), true); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// printMermaid prints the graph as a Mermaid flowchart (https://mermaid.js.org).
+func printMermaid(w io.Writer, g *listGraph) {
+	fmt.Fprintln(w, "graph TD")
+	ids := make(map[string]int)
+	for id, node := range g.Nodes {
+		ids[node.Path] = id
+		fmt.Fprintf(w, "  n%d[%q]\n", id, node.Path)
+	}
+	for _, edge := range g.Edges {
+		if edge.Mode == modePkg.String() {
+			fmt.Fprintf(w, "  n%d --> n%d\n", ids[edge.Src], ids[edge.Dst])
+		} else {
+			fmt.Fprintf(w, "  n%d -. %s .-> n%d\n", ids[edge.Src], edge.Mode, ids[edge.Dst])
+		}
+	}
+	for _, category := range []string{categoryGoroot, categoryThirdParty, categoryInternal} {
+		var nodes []string
+		for id, node := range g.Nodes {
+			if node.Category == category {
+				nodes = append(nodes, fmt.Sprintf("n%d", id))
+			}
+		}
+		if len(nodes) > 0 {
+			fmt.Fprintf(w, "  classDef %s fill:%s\n", category, categoryColors[category])
+			fmt.Fprintf(w, "  class %s %s\n", strings.Join(nodes, ","), category)
+		}
+	}
+}
+
+var categoryColors = map[string]string{
+	categoryGoroot:     "lightgrey",
+	categoryThirdParty: "orange",
+	categoryInternal:   "lightblue",
+}
+
+// clusterOf returns the cluster of the node in a clustered DOT graph: the
+// module containing the package, or the project prefix of its import path.
+func clusterOf(node listNode) string {
+	switch {
+	case node.Goroot:
+		return "GOROOT"
+	case node.Module != "":
+		return node.Module
+	}
+	return groupOf(node.Path, 3)
+}
+
+// printClusteredDot prints the graph as a DOT graph, with the nodes clustered
+// by module or project, and colored by category.
+func printClusteredDot(w io.Writer, g *listGraph) {
+	fmt.Fprintf(w, `digraph {
+  node[shape=record,style=filled,fillcolor=white]
+  edge[arrowhead=vee]
+  graph[rankdir=TB,splines=true]
+`)
+	clusters := make(map[string][]int)
+	for id, node := range g.Nodes {
+		c := clusterOf(node)
+		clusters[c] = append(clusters[c], id)
+	}
+	var names []string
+	for c := range clusters {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	for index, c := range names {
+		fmt.Fprintf(w, "  subgraph cluster_%d {\n    label=%q\n", index, c)
+		for _, id := range clusters[c] {
+			node := g.Nodes[id]
+			attrs := []string{fmt.Sprintf("label=%q", node.Path)}
+			if color := categoryColors[node.Category]; color != "" {
+				attrs = append(attrs, "fillcolor="+color)
+			}
+			fmt.Fprintf(w, "    %d[%s]\n", id, strings.Join(attrs, ","))
+		}
+		fmt.Fprintf(w, "  }\n")
+	}
+	ids := make(map[string]int)
+	for id, node := range g.Nodes {
+		ids[node.Path] = id
+	}
+	for _, edge := range g.Edges {
+		if edge.Mode == modePkg.String() {
+			fmt.Fprintf(w, "  %d->%d\n", ids[edge.Src], ids[edge.Dst])
+		} else {
+			fmt.Fprintf(w, "  %d->%d[style=dashed,label=%q]\n", ids[edge.Src], ids[edge.Dst], edge.Mode)
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
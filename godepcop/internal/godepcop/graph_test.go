@@ -0,0 +1,152 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestPackageCategory(t *testing.T) {
+	tests := []struct {
+		pkg  build.Package
+		want string
+	}{
+		{build.Package{ImportPath: "fmt", Goroot: true}, categoryGoroot},
+		{build.Package{ImportPath: "internal/poll", Goroot: true}, categoryGoroot},
+		{build.Package{ImportPath: "a/third_party/b"}, categoryThirdParty},
+		{build.Package{ImportPath: "third_party/b/internal"}, categoryThirdParty},
+		{build.Package{ImportPath: "a/internal"}, categoryInternal},
+		{build.Package{ImportPath: "a/internal/b"}, categoryInternal},
+		{build.Package{ImportPath: "a/internalx"}, ""},
+		{build.Package{ImportPath: "a/b"}, ""},
+	}
+	for _, test := range tests {
+		if got, want := packageCategory(&test.pkg), test.want; got != want {
+			t.Errorf("packageCategory(%q) got %q, want %q", test.pkg.ImportPath, got, want)
+		}
+	}
+}
+
+func TestBuildListGraph(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	const m = "v.io/x/devtools"
+	tests := []struct {
+		path   string
+		direct bool
+		want   listGraph
+	}{
+		{v + "test-a", false, listGraph{
+			Nodes: []listNode{{v + "test-a", false, m, ""}},
+			Edges: []listEdge{},
+		}},
+		{v + "test-b", false, listGraph{
+			Nodes: []listNode{{v + "test-b", false, m, ""}, {v + "test-c", false, m, ""}, {v + "test-a", false, m, ""}},
+			Edges: []listEdge{{v + "test-b", v + "test-c", "pkg"}, {v + "test-c", v + "test-a", "pkg"}},
+		}},
+		{v + "test-b", true, listGraph{
+			Nodes: []listNode{{v + "test-b", false, m, ""}, {v + "test-c", false, m, ""}},
+			Edges: []listEdge{{v + "test-b", v + "test-c", "pkg"}},
+		}},
+		{v + "test-internal", true, listGraph{
+			Nodes: []listNode{
+				{v + "test-internal", false, m, ""},
+				{v + "test-internal/internal", false, m, categoryInternal},
+				{v + "test-internal/internal/child", false, m, categoryInternal},
+			},
+			Edges: []listEdge{
+				{v + "test-internal", v + "test-internal/internal", "pkg"},
+				{v + "test-internal", v + "test-internal/internal/child", "pkg"},
+			},
+		}},
+	}
+	for _, test := range tests {
+		pkg, err := importPackage(test.path)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		g, err := buildListGraph([]*build.Package{pkg}, depOpts{DirectOnly: test.direct})
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		if got, want := *g, test.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v direct=%v got %v, want %v", test.path, test.direct, got, want)
+		}
+	}
+}
+
+var testListGraph = &listGraph{
+	Nodes: []listNode{
+		{"a/b", false, "a", ""},
+		{"a/b/internal", false, "a", categoryInternal},
+		{"fmt", true, "", categoryGoroot},
+		{"c/third_party/d", false, "", categoryThirdParty},
+	},
+	Edges: []listEdge{
+		{"a/b", "a/b/internal", "pkg"},
+		{"a/b", "fmt", "pkg"},
+		{"a/b", "c/third_party/d", "xtest"},
+		{"a/b/internal", "fmt", "pkg"},
+	},
+}
+
+func TestPrintMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	printMermaid(&buf, testListGraph)
+	want := `graph TD
+  n0["a/b"]
+  n1["a/b/internal"]
+  n2["fmt"]
+  n3["c/third_party/d"]
+  n0 --> n1
+  n0 --> n2
+  n0 -. xtest .-> n3
+  n1 --> n2
+  classDef goroot fill:lightgrey
+  class n2 goroot
+  classDef third_party fill:orange
+  class n3 third_party
+  classDef internal fill:lightblue
+  class n1 internal
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPrintClusteredDot(t *testing.T) {
+	var buf bytes.Buffer
+	printClusteredDot(&buf, testListGraph)
+	want := `digraph {
+  node[shape=record,style=filled,fillcolor=white]
+  edge[arrowhead=vee]
+  graph[rankdir=TB,splines=true]
+  subgraph cluster_0 {
+    label="GOROOT"
+    2[label="fmt",fillcolor=lightgrey]
+  }
+  subgraph cluster_1 {
+    label="a"
+    0[label="a/b"]
+    1[label="a/b/internal",fillcolor=lightblue]
+  }
+  subgraph cluster_2 {
+    label="c/third_party/d"
+    3[label="c/third_party/d",fillcolor=orange]
+  }
+  0->1
+  0->2
+  0->3[style=dashed,label="xtest"]
+  1->2
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"go/build"
+	"sync"
+)
+
+// pkgImports is the import graph shared by all checks, which is reset along
+// with the package cache by setBuildContext.
+var pkgImports = newImportGraph()
+
+// importGraph memoizes the transitive imports of each package, so that they
+// are computed only once across all checked packages and modes.  It is safe
+// for concurrent use.
+type importGraph struct {
+	mu         sync.Mutex
+	transitive map[string]map[string]*build.Package
+}
+
+func newImportGraph() *importGraph {
+	return &importGraph{transitive: make(map[string]map[string]*build.Package)}
+}
+
+// Deps fills deps with the dependencies of pkg, like opts.Deps.
+func (g *importGraph) Deps(pkg *build.Package, opts depOpts, deps map[string]*build.Package) error {
+	for _, path := range opts.Paths(pkg) {
+		dep, err := importPackage(path)
+		if err != nil {
+			return err
+		}
+		if !opts.IncludeGoroot && dep.Goroot {
+			continue
+		}
+		deps[path] = dep
+		if opts.DirectOnly {
+			continue
+		}
+		transitive, err := g.Transitive(dep)
+		if err != nil {
+			return err
+		}
+		for path, dep := range transitive {
+			// $GOROOT packages only import other $GOROOT packages, so skipping them
+			// here matches the traversal of opts.Deps.
+			if opts.IncludeGoroot || !dep.Goroot {
+				deps[path] = dep
+			}
+		}
+	}
+	return nil
+}
+
+// Transitive returns the transitive imports of pkg, excluding imports from test
+// files, but including $GOROOT packages.  The returned map must not be
+// modified.
+func (g *importGraph) Transitive(pkg *build.Package) (map[string]*build.Package, error) {
+	g.mu.Lock()
+	deps, ok := g.transitive[pkg.ImportPath]
+	g.mu.Unlock()
+	if ok {
+		return deps, nil
+	}
+	// Go doesn't allow import cycles, so the recursion terminates.  Concurrent
+	// callers may compute the same result; the first one to finish wins.
+	deps = make(map[string]*build.Package)
+	for _, path := range pkg.Imports {
+		dep, err := importPackage(path)
+		if err != nil {
+			return nil, err
+		}
+		deps[path] = dep
+		transitive, err := g.Transitive(dep)
+		if err != nil {
+			return nil, err
+		}
+		for path, dep := range transitive {
+			deps[path] = dep
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cached, ok := g.transitive[pkg.ImportPath]; ok {
+		return cached, nil
+	}
+	g.transitive[pkg.ImportPath] = deps
+	return deps, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestImportGraphDeps(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	paths := []string{"test-a", "test-b", "test-c", "test-e", "test-internal", "test-layers/lib/bad", "import-C"}
+	g := newImportGraph()
+	for _, path := range paths {
+		pkg, err := importPackage(v + path)
+		if err != nil {
+			t.Fatalf("%v failed: %v", path, err)
+		}
+		for _, mode := range allModes {
+			for _, goroot := range []bool{false, true} {
+				opts := mode.DepOpts()
+				opts.IncludeGoroot = goroot
+				want := make(map[string]bool)
+				deps := make(map[string]*build.Package)
+				if err := opts.Deps(pkg, deps); err != nil {
+					t.Fatalf("%v failed: %v", path, err)
+				}
+				for path := range deps {
+					want[path] = true
+				}
+				got := make(map[string]bool)
+				deps = make(map[string]*build.Package)
+				if err := g.Deps(pkg, opts, deps); err != nil {
+					t.Fatalf("%v failed: %v", path, err)
+				}
+				for path := range deps {
+					got[path] = true
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%v %v goroot=%v got %v, want %v", path, mode, goroot, got, want)
+				}
+			}
+		}
+	}
+}
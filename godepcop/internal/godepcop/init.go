@@ -0,0 +1,313 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// generateConfig returns a minimal config for pkg, which allows exactly the
+// current transitive imports of pkg, and denies everything else.  Imports are
+// collapsed into "/..." patterns where all of the packages below a path in the
+// sorted universe of known packages are imported.
+func generateConfig(pkg *build.Package, universe []string) (*config, error) {
+	var prev map[string]*build.Package
+	c := &config{}
+	for _, mode := range allModes {
+		deps := make(map[string]*build.Package)
+		opts := mode.DepOpts()
+		opts.IncludeGoroot = false
+		if err := pkgImports.Deps(pkg, opts, deps); err != nil {
+			return nil, err
+		}
+		// Only allow the imports that aren't already allowed in a narrower mode.
+		var added []string
+		for path := range deps {
+			if prev[path] == nil {
+				added = append(added, path)
+			}
+		}
+		var rules []rule
+		for _, pattern := range collapsePaths(added, deps, universe) {
+			pattern := pattern
+			rules = append(rules, rule{Allow: &pattern})
+		}
+		switch mode {
+		case modePkg:
+			dots := "..."
+			c.PkgRules = append(rules, rule{Deny: &dots})
+		case modeTest:
+			c.TestRules = rules
+		case modeXTest:
+			c.XTestRules = rules
+		}
+		prev = deps
+	}
+	return c, nil
+}
+
+// collapsePaths returns the patterns that match the given paths.  A path is
+// replaced by the "/..." pattern of the shortest prefix of the path within the
+// same module, if all packages in the universe below that prefix are in
+// allowed, and there are at least two of them.  Outside of a module, e.g. in
+// GOPATH mode, the prefix must have at least two elements, so that it is
+// never just a host name.  The universe must be sorted.
+func collapsePaths(paths []string, allowed map[string]*build.Package, universe []string) []string {
+	patterns := make(map[string]bool)
+	for _, path := range paths {
+		pattern := path
+		elems := strings.Split(path, "/")
+		module := modulePaths[path]
+		for i := 1; i <= len(elems); i++ {
+			prefix := strings.Join(elems[:i], "/")
+			if len(prefix) < len(module) || module == "" && i < 2 {
+				continue
+			}
+			if below := packagesBelow(prefix, universe); len(below) > 1 && allAllowed(below, allowed) {
+				pattern = prefix + "/..."
+				break
+			}
+		}
+		patterns[pattern] = true
+	}
+	var result []string
+	for pattern := range patterns {
+		result = append(result, pattern)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// packagesBelow returns the paths in the sorted universe that are prefix or
+// below it.
+func packagesBelow(prefix string, universe []string) []string {
+	var below []string
+	if i := sort.SearchStrings(universe, prefix); i < len(universe) && universe[i] == prefix {
+		below = append(below, prefix)
+	}
+	// All paths starting with prefix+"/" sort before prefix+"0".
+	begin := sort.SearchStrings(universe, prefix+"/")
+	end := sort.SearchStrings(universe, prefix+"0")
+	return append(below, universe[begin:end]...)
+}
+
+func allAllowed(paths []string, allowed map[string]*build.Package) bool {
+	for _, path := range paths {
+		if allowed[path] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// tightenConfig drops the pkg, test and xtest allow rules of cfg that don't
+// match any transitive import of the given pkgs, and returns the dropped rules.
+func tightenConfig(cfg *config, pkgs []*build.Package) ([]rule, error) {
+	deps := make(map[string]*build.Package)
+	for _, pkg := range pkgs {
+		if err := pkgImports.Deps(pkg, modeXTest.DepOpts(), deps); err != nil {
+			return nil, err
+		}
+	}
+	var dropped []rule
+	tighten := func(rules []rule) ([]rule, error) {
+		var kept []rule
+		for _, r := range rules {
+			used := r.IsDeny()
+			for _, dep := range deps {
+				if used {
+					break
+				}
+				result, err := enforceRule(r, dep)
+				if err != nil {
+					return nil, err
+				}
+				used = result == resultApproved
+			}
+			if used {
+				kept = append(kept, r)
+			} else {
+				dropped = append(dropped, r)
+			}
+		}
+		return kept, nil
+	}
+	var err error
+	if cfg.PkgRules, err = tighten(cfg.PkgRules); err != nil {
+		return nil, err
+	}
+	if cfg.TestRules, err = tighten(cfg.TestRules); err != nil {
+		return nil, err
+	}
+	if cfg.XTestRules, err = tighten(cfg.XTestRules); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// configsOf returns the paths of the existing .godepcop files that apply to
+// pkgs, along with the packages that each of them applies to.
+func configsOf(pkgs []*build.Package) ([]string, map[string][]*build.Package, error) {
+	governed := make(map[string][]*build.Package)
+	for _, pkg := range pkgs {
+		it := newConfigIter(pkg)
+		for it.Advance() {
+			if path := it.Value().Path; fileExists(path) {
+				governed[path] = append(governed[path], pkg)
+			}
+		}
+		if err := it.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	var paths []string
+	for path := range governed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, governed, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// marshalConfig returns the XML encoding of cfg.
+func marshalConfig(cfg *config) ([]byte, error) {
+	data, err := xml.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("MarshalIndent(%v) failed: %v", cfg, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// writeConfig writes cfg to the given path, and returns the written data.
+func writeConfig(path string, cfg *config) ([]byte, error) {
+	data, err := marshalConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return data, writeConfigData(path, data)
+}
+
+// rewriteConfig rewrites the .godepcop file at the given path, which holds
+// orig, without the pkg, test and xtest rules that were dropped from orig to
+// get cfg, and returns the written data.  Everything else in the file, such as
+// comments, is left as it is.
+func rewriteConfig(path string, orig, cfg *config) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	drop := map[string]map[int]bool{
+		"pkg":   droppedRules(orig.PkgRules, cfg.PkgRules),
+		"test":  droppedRules(orig.TestRules, cfg.TestRules),
+		"xtest": droppedRules(orig.XTestRules, cfg.XTestRules),
+	}
+	if data, err = removeElements(data, drop); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return data, writeConfigData(path, data)
+}
+
+// droppedRules returns the indices of the rules in orig that aren't in kept,
+// which holds the rest of them in the same order.
+func droppedRules(orig, kept []rule) map[int]bool {
+	dropped := make(map[int]bool)
+	for i, r := range orig {
+		if len(kept) > 0 && reflect.DeepEqual(r, kept[0]) {
+			kept = kept[1:]
+			continue
+		}
+		dropped[i] = true
+	}
+	return dropped
+}
+
+// removeElements returns data, an XML document, without the children of the
+// root element given by drop, which maps their names to the indices of the
+// ones to remove among the children with the same name.  The lines that only
+// hold a removed element are removed entirely.
+func removeElements(data []byte, drop map[string]map[int]bool) ([]byte, error) {
+	var result []byte
+	last, depth := 0, 0
+	counts := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		start := int(dec.InputOffset())
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth++; depth != 2 {
+				continue
+			}
+			name := t.Name.Local
+			index := counts[name]
+			counts[name]++
+			if !drop[name][index] {
+				continue
+			}
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+			depth--
+			start, end := lineRange(data, start, int(dec.InputOffset()))
+			result = append(result, data[last:start]...)
+			last = end
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return append(result, data[last:]...), nil
+}
+
+// lineRange returns the range of the lines of data that hold the range from
+// start to end, if there is nothing else on them but white space, and the
+// range itself otherwise.
+func lineRange(data []byte, start, end int) (int, int) {
+	lineStart := start
+	for lineStart > 0 && (data[lineStart-1] == ' ' || data[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(data) && (data[lineEnd] == ' ' || data[lineEnd] == '\t' || data[lineEnd] == '\r') {
+		lineEnd++
+	}
+	if (lineStart > 0 && data[lineStart-1] != '\n') || (lineEnd < len(data) && data[lineEnd] != '\n') {
+		return start, end
+	}
+	if lineEnd < len(data) {
+		lineEnd++
+	}
+	return lineStart, lineEnd
+}
+
+// writeConfigData writes the .godepcop file at the given path.
+func writeConfigData(path string, data []byte) error {
+	if err := ioutil.WriteFile(path, data, os.FileMode(0644)); err != nil {
+		return err
+	}
+	// Drop the stale cached config.
+	configMu.Lock()
+	delete(configCache, path)
+	configMu.Unlock()
+	return nil
+}
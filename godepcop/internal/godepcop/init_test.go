@@ -0,0 +1,186 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollapsePaths(t *testing.T) {
+	universe := []string{"m/a", "m/a/b", "m/a/c", "m/a/c/d", "m/ab", "m/e", "m/e/f", "n/x", "n/y", "n/z/a", "n/z/b"}
+	for _, path := range universe {
+		module := "m"
+		if path[0] == 'n' {
+			module = ""
+		}
+		modulePaths[path] = module
+		defer delete(modulePaths, path)
+	}
+	tests := []struct {
+		allowed []string
+		want    []string
+	}{
+		{[]string{"m/a/b"}, []string{"m/a/b"}},
+		{[]string{"m/a/b", "m/a/c"}, []string{"m/a/b", "m/a/c"}},
+		{[]string{"m/a/c", "m/a/c/d"}, []string{"m/a/c/..."}},
+		{[]string{"m/a", "m/a/b", "m/a/c", "m/a/c/d"}, []string{"m/a/..."}},
+		{[]string{"m/a", "m/a/b", "m/a/c", "m/a/c/d", "m/e", "m/e/f"}, []string{"m/a/...", "m/e/..."}},
+		{universe[:7], []string{"m/..."}},
+		// Paths outside of a module are only collapsed below their
+		// first element.
+		{[]string{"n/x", "n/y"}, []string{"n/x", "n/y"}},
+		{[]string{"n/x", "n/y", "n/z/a", "n/z/b"}, []string{"n/x", "n/y", "n/z/..."}},
+	}
+	for _, test := range tests {
+		allowed := make(map[string]*build.Package)
+		for _, path := range test.allowed {
+			allowed[path] = pkg(path)
+		}
+		if got, want := collapsePaths(test.allowed, allowed, universe), test.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got %v, want %v", test.allowed, got, want)
+		}
+	}
+}
+
+func TestGenerateConfig(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	var universe []string
+	for _, path := range []string{"test-a", "test-b", "test-c", "test-c/child", "test-internal", "test-internal/child", "test-internal/internal", "test-internal/internal/child"} {
+		universe = append(universe, v+path)
+	}
+	ss := func(patterns ...string) []rule {
+		var rules []rule
+		for _, pattern := range patterns {
+			rules = append(rules, allow(v+pattern))
+		}
+		return rules
+	}
+	tests := []struct {
+		path string
+		want []rule
+	}{
+		{"test-a", []rule{deny("...")}},
+		{"test-b", append(ss("test-a", "test-c"), deny("..."))},
+		{"test-internal", append(ss("test-internal/internal/..."), deny("..."))},
+	}
+	for _, test := range tests {
+		p, err := importPackage(v + test.path)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		cfg, err := generateConfig(p, universe)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		want := &config{PkgRules: test.want}
+		if got := cfg; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got %v, want %v", test.path, got, want)
+		}
+		// The generated config must survive a round trip.
+		data, err := marshalConfig(cfg)
+		if err != nil {
+			t.Errorf("%v failed: %v", test.path, err)
+			continue
+		}
+		parsed, err := parseConfig(data)
+		if err != nil {
+			t.Errorf("%v: parseConfig(%s) failed: %v", test.path, data, err)
+			continue
+		}
+		if got := parsed; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got %v, want %v", test.path, got, want)
+		}
+	}
+}
+
+func TestTightenConfig(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	p, err := importPackage(v + "test-c")
+	if err != nil {
+		t.Fatalf("importPackage failed: %v", err)
+	}
+	cfg := &config{
+		PkgRules:      []rule{allow(v + "test-a"), allow(v + "test-b"), allow("fmt"), deny("...")},
+		TestRules:     []rule{allow("foo/...")},
+		ImporterRules: []rule{allow("bar/...")},
+	}
+	dropped, err := tightenConfig(cfg, []*build.Package{p})
+	if err != nil {
+		t.Fatalf("tightenConfig failed: %v", err)
+	}
+	if got, want := dropped, []rule{allow(v + "test-b"), allow("foo/...")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dropped %v, want %v", got, want)
+	}
+	want := &config{
+		PkgRules:      []rule{allow(v + "test-a"), allow("fmt"), deny("...")},
+		ImporterRules: []rule{allow("bar/...")},
+	}
+	if got := cfg; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRewriteConfig(t *testing.T) {
+	const data = `<godepcop>
+  <!-- Comments are kept. -->
+  <pkg allow="a"/>
+  <pkg allow="b"/> <!-- So are rules that share a line. -->
+  <pkg deny="..."/>
+  <test allow="c"/><test allow="a"/>
+  <xtest allow="a"/>
+  <xtest allow="a"/>
+  <importers allow="a"/>
+</godepcop>
+`
+	dir, err := ioutil.TempDir("", "godepcop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, configFileName)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := *orig
+	cfg.PkgRules = []rule{allow("b"), deny("...")}
+	cfg.TestRules = []rule{allow("c")}
+	cfg.XTestRules = []rule{allow("a")}
+	got, err := rewriteConfig(path, orig, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<godepcop>
+  <!-- Comments are kept. -->
+  <pkg allow="b"/> <!-- So are rules that share a line. -->
+  <pkg deny="..."/>
+  <test allow="c"/>
+  <xtest allow="a"/>
+  <importers allow="a"/>
+</godepcop>
+`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// The stale cached config must have been dropped.
+	reloaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Path = path
+	if !reflect.DeepEqual(reloaded, &cfg) {
+		t.Errorf("got %v, want %v", reloaded, &cfg)
+	}
+}
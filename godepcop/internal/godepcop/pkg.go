@@ -0,0 +1,314 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"v.io/x/lib/set"
+)
+
+var (
+	pseudoPackageC      = &build.Package{ImportPath: "C", Goroot: true}
+	pseudoPackageUnsafe = &build.Package{ImportPath: "unsafe", Goroot: true}
+
+	// pkgMu guards pkgCache, pkgErrors, modulePaths and moduleDirs, which are
+	// filled lazily by the workers checking packages concurrently.
+	pkgMu       sync.Mutex
+	pkgCache    = map[string]*build.Package{"C": pseudoPackageC, "unsafe": pseudoPackageUnsafe}
+	pkgErrors   = map[string]error{}
+	modulePaths = map[string]string{}
+	// moduleDirs maps the import paths of packages in modules to the root
+	// directories of their modules, which bound the .godepcop lookup.
+	moduleDirs = map[string]string{}
+)
+
+func isPseudoPackage(p *build.Package) bool {
+	return p == pseudoPackageUnsafe || p == pseudoPackageC
+}
+
+// goListPackage describes a single package in the output of "go list -json".
+// Most fields share their name and meaning with build.Package, so we decode
+// directly into an embedded build.Package and only add the fields that are
+// specific to go list.
+type goListPackage struct {
+	build.Package
+	DepOnly bool
+	ForTest string
+	Match   []string
+	Module  *struct {
+		Path string
+		Dir  string
+	}
+	Error *struct {
+		Err string
+	}
+}
+
+// runGoList runs "go list -e -json" with the given extra args in dir, or the
+// current directory if dir is empty, and returns the decoded packages.  The
+// packages are loaded for the current platform and build tags, with env added
+// to the environment.
+func runGoList(dir string, env []string, args ...string) ([]goListPackage, error) {
+	flags := []string{"list", "-e", "-json"}
+	if loadTags != "" {
+		flags = append(flags, "-tags="+loadTags)
+	}
+	args = append(flags, args...)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), loadPlatform.Env()...), env...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	var pkgs []goListPackage
+	for dec := json.NewDecoder(&stdout); dec.More(); {
+		var lp goListPackage
+		if err := dec.Decode(&lp); err != nil {
+			return nil, fmt.Errorf("go %s: %v", strings.Join(args, " "), err)
+		}
+		pkgs = append(pkgs, lp)
+	}
+	return pkgs, nil
+}
+
+// checkMatched returns an error if any of the patterns didn't match any of the
+// listed packages.  The go tool only warns about such patterns, which would
+// otherwise leave nothing to check without any indication.
+func checkMatched(patterns []string, listed []goListPackage) error {
+	matched := make(map[string]bool)
+	for _, lp := range listed {
+		for _, pattern := range lp.Match {
+			matched[pattern] = true
+		}
+	}
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			return fmt.Errorf("pattern %q matched no packages", pattern)
+		}
+	}
+	return nil
+}
+
+// goList runs "go list" over the given patterns, and adds every listed package
+// and all of its dependencies to pkgCache.  Packages are resolved by the go tool
+// itself, so module replace directives, workspaces and vendor directories are
+// all handled the same way as in "go build".  Returns the packages that matched
+// the patterns, in the order they were listed.
+func goList(patterns ...string) ([]*build.Package, error) {
+	listed, err := runGoList("", nil, append([]string{"-deps"}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMatched(patterns, listed); err != nil {
+		return nil, err
+	}
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	var matched []*build.Package
+	for i := range listed {
+		lp := &listed[i]
+		p, ok := pkgCache[lp.ImportPath]
+		if !ok {
+			p = &lp.Package
+			if lp.Module != nil {
+				moduleDirs[p.ImportPath] = lp.Module.Dir
+				modulePaths[p.ImportPath] = lp.Module.Path
+			}
+			pkgCache[p.ImportPath] = p
+			// The go tool also reports import errors, like violations of the
+			// internal package rule, on otherwise valid packages.  We only treat
+			// packages that couldn't be loaded at all as errors; the rest is
+			// checked by godepcop itself.
+			if lp.Error != nil && lp.Name == "" {
+				pkgErrors[p.ImportPath] = errors.New(lp.Error.Err)
+			}
+		}
+		if !lp.DepOnly {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// loadPackages loads and returns the packages matching the given patterns.
+func loadPackages(patterns ...string) ([]*build.Package, error) {
+	pkgs, err := goList(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	for _, pkg := range pkgs {
+		if err := pkgErrors[pkg.ImportPath]; err != nil {
+			return nil, err
+		}
+	}
+	return pkgs, nil
+}
+
+// importPackage loads and returns the package with the given package path.
+func importPackage(path string) (*build.Package, error) {
+	if p, ok, err := cachedPackage(path); ok {
+		return p, err
+	}
+	if _, err := goList(path); err != nil {
+		return nil, err
+	}
+	p, ok, err := cachedPackage(path)
+	if !ok {
+		return nil, fmt.Errorf("package %q not found", path)
+	}
+	return p, err
+}
+
+// knownPackages returns the sorted import paths of all loaded packages, except
+// for $GOROOT packages.
+func knownPackages() []string {
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	var paths []string
+	for path, pkg := range pkgCache {
+		if !pkg.Goroot {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func cachedPackage(path string) (*build.Package, bool, error) {
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	p, ok := pkgCache[path]
+	return p, ok, pkgErrors[path]
+}
+
+// depOpts holds options for computing package dependencies.
+type depOpts struct {
+	DirectOnly    bool // Only compute direct (rather than transitive) deps.
+	IncludeGoroot bool // Include $GOROOT packages.
+	IncludeTest   bool // Also include TestImports
+	IncludeXTest  bool // Also include TestImports and XTestImports.
+}
+
+// Paths returns the initial package paths to use when computing dependencies.
+func (x depOpts) Paths(pkg *build.Package) []string {
+	uniq := map[string]struct{}{}
+	set.String.Union(uniq, set.String.FromSlice(pkg.Imports))
+	if x.IncludeTest || x.IncludeXTest {
+		set.String.Union(uniq, set.String.FromSlice(pkg.TestImports))
+	}
+	if x.IncludeXTest {
+		set.String.Union(uniq, set.String.FromSlice(pkg.XTestImports))
+	}
+	// Don't include the package itself; it was added by XTestImports.
+	delete(uniq, pkg.ImportPath)
+	paths := set.String.ToSlice(uniq)
+	sort.Strings(paths)
+	return paths
+}
+
+// PrintIdent prints pkg and its dependencies to w, with fancy indentation.
+func (x depOpts) PrintIndent(w io.Writer, pkg *build.Package) error {
+	fmt.Fprintln(w, "#"+pkg.ImportPath)
+	return x.printIndentHelper(w, x.Paths(pkg), 0)
+}
+
+func (x depOpts) printIndentHelper(w io.Writer, paths []string, depth int) error {
+	for _, path := range paths {
+		pkg, err := importPackage(path)
+		if err != nil {
+			return err
+		}
+		if !x.IncludeGoroot && pkg.Goroot {
+			continue
+		}
+		fmt.Fprintln(w, strings.Repeat(" │", depth)+" ├─"+pkg.ImportPath)
+		if !x.DirectOnly {
+			if err := x.printIndentHelper(w, pkg.Imports, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Deps fills deps with the dependencies of pkg.  If directOnly is true, only
+// direct dependencies are printed, not transitive dependencies.
+func (x depOpts) Deps(pkg *build.Package, deps map[string]*build.Package) error {
+	return x.depsHelper(x.Paths(pkg), deps)
+}
+
+func (x depOpts) depsHelper(paths []string, deps map[string]*build.Package) error {
+	for _, path := range paths {
+		if deps[path] != nil {
+			continue
+		}
+		pkg, err := importPackage(path)
+		if err != nil {
+			return err
+		}
+		if !x.IncludeGoroot && pkg.Goroot {
+			continue
+		}
+		deps[path] = pkg
+		if !x.DirectOnly {
+			if err := x.depsHelper(pkg.Imports, deps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasOverlap(a, b map[string]*build.Package) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for key, _ := range a {
+		if b[key] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+type pkgSorter []*build.Package
+
+func (s pkgSorter) Len() int           { return len(s) }
+func (s pkgSorter) Less(i, j int) bool { return s[i].ImportPath < s[j].ImportPath }
+func (s pkgSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// sortPackages returns the packages in pkgs, sorting all GOROOT packages first,
+// followed by sorted non-GOROOT packages.
+func sortPackages(pkgs map[string]*build.Package) []*build.Package {
+	var roots, nonroots pkgSorter
+	for _, pkg := range pkgs {
+		if pkg.Goroot {
+			roots = append(roots, pkg)
+		} else {
+			nonroots = append(nonroots, pkg)
+		}
+	}
+	sort.Sort(roots)
+	sort.Sort(nonroots)
+	var result []*build.Package
+	result = append(result, roots...)
+	result = append(result, nonroots...)
+	return result
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package godepcop
 
 import (
 	"go/build"
@@ -70,3 +70,18 @@ func TestPackageDeps(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadPackagesUnmatched(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	// Wildcards never match packages in testdata directories.
+	if _, err := loadPackages(v+"test-a", v+"test-b/..."); err == nil {
+		t.Errorf("loadPackages with a pattern that matches no packages succeeded, want an error")
+	}
+	pkgs, err := loadPackages(v + "test-a")
+	if err != nil {
+		t.Fatalf("loadPackages failed: %v", err)
+	}
+	if got, want := len(pkgs), 1; got != want {
+		t.Errorf("got %d packages, want %d", got, want)
+	}
+}
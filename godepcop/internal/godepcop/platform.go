@@ -0,0 +1,157 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"fmt"
+	"go/build"
+	"strings"
+)
+
+// platform is a GOOS/GOARCH pair that packages may be loaded for.  The zero
+// platform is the host platform.
+type platform struct {
+	OS, Arch string
+}
+
+func (p platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// Env returns the environment variables that select the platform.
+func (p platform) Env() []string {
+	if p == (platform{}) {
+		return nil
+	}
+	// Cgo is disabled by default when cross-compiling, which would hide imports
+	// from cgo files.  Listing packages doesn't require a C toolchain.
+	return []string{"GOOS=" + p.OS, "GOARCH=" + p.Arch, "CGO_ENABLED=1"}
+}
+
+// parsePlatforms parses a comma-separated list of GOOS/GOARCH pairs.
+func parsePlatforms(s string) ([]platform, error) {
+	var platforms []platform
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		parts := strings.Split(field, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH", field)
+		}
+		platforms = append(platforms, platform{parts[0], parts[1]})
+	}
+	return platforms, nil
+}
+
+// parseTagSets parses a semicolon-separated list of build tag sets, each of
+// which is a comma-separated list of tags.  An empty set, e.g. the first one in
+// ";a,b", selects no additional tags.  An empty list is a single empty set.
+func parseTagSets(s string) []string {
+	var sets []string
+	for _, set := range strings.Split(s, ";") {
+		sets = append(sets, strings.TrimSpace(set))
+	}
+	return sets
+}
+
+// buildContext is a platform and a set of build tags that packages may be
+// loaded for.
+type buildContext struct {
+	platform platform
+	tags     string
+}
+
+// String returns the label of the context that violations are tagged with,
+// e.g. "linux/amd64", "linux/amd64+tags=a,b" or "host+tags=a,b".
+func (c buildContext) String() string {
+	label := "host"
+	if c.platform != (platform{}) {
+		label = c.platform.String()
+	}
+	if c.tags != "" {
+		label += "+tags=" + c.tags
+	}
+	return label
+}
+
+var (
+	loadPlatform platform // The platform that packages are loaded for.
+	loadTags     string   // The build tags that packages are loaded with.
+)
+
+// setBuildContext sets the platform and build tags that packages are loaded
+// for.  The package cache is cleared, since packages may have different files
+// and imports in the new context.
+func setBuildContext(p platform, tags string) {
+	loadPlatform, loadTags = p, tags
+	pkgMu.Lock()
+	pkgCache = map[string]*build.Package{"C": pseudoPackageC, "unsafe": pseudoPackageUnsafe}
+	pkgErrors = map[string]error{}
+	modulePaths = map[string]string{}
+	moduleDirs = map[string]string{}
+	pkgMu.Unlock()
+	pkgImports = newImportGraph()
+}
+
+// checkPlatforms runs check for each of the given platforms with each of the
+// given tag sets, and merges the results.  Violations that occur in several of
+// these build contexts are reported once, and are tagged with all of them.  If
+// platforms is empty, check is run for the host platform, and if there is also
+// at most one tag set, it is run once and violations are not tagged.
+func checkPlatforms(platforms []platform, tagSets []string, check func() ([]*build.Package, []violation, error)) ([]*build.Package, []violation, error) {
+	if len(platforms) == 0 && len(tagSets) <= 1 {
+		tags := ""
+		if len(tagSets) == 1 {
+			tags = tagSets[0]
+		}
+		setBuildContext(platform{}, tags)
+		return check()
+	}
+	if len(platforms) == 0 {
+		platforms = []platform{{}}
+	}
+	if len(tagSets) == 0 {
+		tagSets = []string{""}
+	}
+	var contexts []buildContext
+	for _, p := range platforms {
+		for _, tags := range tagSets {
+			contexts = append(contexts, buildContext{p, tags})
+		}
+	}
+	type key struct {
+		src, dst, err string
+		mode          checkMode
+	}
+	var pkgs []*build.Package
+	var violations []violation
+	seenPkgs := make(map[string]bool)
+	seenViolations := make(map[key]int)
+	for _, c := range contexts {
+		setBuildContext(c.platform, c.tags)
+		ps, vs, err := check()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", c, err)
+		}
+		for _, pkg := range ps {
+			if !seenPkgs[pkg.ImportPath] {
+				seenPkgs[pkg.ImportPath] = true
+				pkgs = append(pkgs, pkg)
+			}
+		}
+		for _, v := range vs {
+			k := key{v.Src.ImportPath, v.Dst.ImportPath, v.Err.Error(), v.Mode}
+			if index, ok := seenViolations[k]; ok {
+				violations[index].Platforms = append(violations[index].Platforms, c.String())
+				continue
+			}
+			seenViolations[k] = len(violations)
+			v.Platforms = []string{c.String()}
+			violations = append(violations, v)
+		}
+	}
+	return pkgs, violations, nil
+}
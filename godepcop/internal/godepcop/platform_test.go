@@ -0,0 +1,127 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"errors"
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	tests := []struct {
+		s         string
+		platforms []platform
+		err       bool
+	}{
+		{"", nil, false},
+		{"linux/amd64", []platform{{"linux", "amd64"}}, false},
+		{"linux/amd64, android/arm,", []platform{{"linux", "amd64"}, {"android", "arm"}}, false},
+		{"linux", nil, true},
+		{"linux/", nil, true},
+		{"linux/amd64/x", nil, true},
+	}
+	for _, test := range tests {
+		platforms, err := parsePlatforms(test.s)
+		if got, want := err != nil, test.err; got != want {
+			t.Errorf("parsePlatforms(%q) got error %v, want error %v", test.s, err, want)
+		}
+		if got, want := platforms, test.platforms; !reflect.DeepEqual(got, want) {
+			t.Errorf("parsePlatforms(%q) got %v, want %v", test.s, got, want)
+		}
+	}
+}
+
+func TestCheckPlatforms(t *testing.T) {
+	errDeny := errors.New("denied")
+	a, b, c := pkg("a"), pkg("b"), pkg("c")
+	results := map[platform][]violation{
+		{"linux", "amd64"}:  {{Src: a, Dst: b, Err: errDeny}},
+		{"android", "arm"}:  {{Src: a, Dst: b, Err: errDeny}, {Src: a, Dst: c, Err: errDeny}},
+		{"darwin", "amd64"}: nil,
+	}
+	platforms := []platform{{"linux", "amd64"}, {"android", "arm"}, {"darwin", "amd64"}}
+	pkgs, violations, err := checkPlatforms(platforms, []string{""}, func() ([]*build.Package, []violation, error) {
+		return []*build.Package{a}, results[loadPlatform], nil
+	})
+	if err != nil {
+		t.Fatalf("checkPlatforms failed: %v", err)
+	}
+	if got, want := pkgs, []*build.Package{a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got pkgs %v, want %v", got, want)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		`"a" not allowed to import "b" (denied) [linux/amd64 android/arm]`,
+		`"a" not allowed to import "c" (denied) [android/arm]`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	setBuildContext(platform{}, "")
+}
+
+func TestParseTagSets(t *testing.T) {
+	tests := []struct {
+		s    string
+		sets []string
+	}{
+		{"", []string{""}},
+		{"a,b", []string{"a,b"}},
+		{"a,b; c", []string{"a,b", "c"}},
+		{";c", []string{"", "c"}},
+	}
+	for _, test := range tests {
+		if got, want := parseTagSets(test.s), test.sets; !reflect.DeepEqual(got, want) {
+			t.Errorf("parseTagSets(%q) got %q, want %q", test.s, got, want)
+		}
+	}
+}
+
+func TestCheckPlatformsTagSets(t *testing.T) {
+	errDeny := errors.New("denied")
+	a, b, c := pkg("a"), pkg("b"), pkg("c")
+	linux := platform{"linux", "amd64"}
+	results := map[buildContext][]violation{
+		{linux, ""}:       {{Src: a, Dst: b, Err: errDeny}},
+		{linux, "x"}:      {{Src: a, Dst: b, Err: errDeny}, {Src: a, Dst: c, Err: errDeny}},
+		{platform{}, "x"}: {{Src: a, Dst: c, Err: errDeny}},
+	}
+	for _, test := range []struct {
+		platforms []platform
+		want      []string
+	}{
+		{
+			[]platform{linux},
+			[]string{
+				`"a" not allowed to import "b" (denied) [linux/amd64 linux/amd64+tags=x]`,
+				`"a" not allowed to import "c" (denied) [linux/amd64+tags=x]`,
+			},
+		},
+		{
+			nil,
+			[]string{`"a" not allowed to import "c" (denied) [host+tags=x]`},
+		},
+	} {
+		_, violations, err := checkPlatforms(test.platforms, []string{"", "x"}, func() ([]*build.Package, []violation, error) {
+			return []*build.Package{a}, results[buildContext{loadPlatform, loadTags}], nil
+		})
+		if err != nil {
+			t.Fatalf("checkPlatforms failed: %v", err)
+		}
+		var got []string
+		for _, v := range violations {
+			got = append(got, v.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %q, want %q", test.platforms, got, test.want)
+		}
+	}
+	setBuildContext(platform{}, "")
+}
@@ -0,0 +1,256 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/build"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"v.io/x/devtools/internal/xunit"
+)
+
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+	formatXUnit = "xunit"
+)
+
+// violationRecord is the machine-readable form of a violation.
+type violationRecord struct {
+	Src       string   `json:"src"`
+	Dst       string   `json:"dst"`
+	Mode      string   `json:"mode"`
+	Rule      string   `json:"rule,omitempty"`
+	Config    string   `json:"config,omitempty"`
+	Chain     []string `json:"chain"`
+	Error     string   `json:"error"`
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+func (v violation) Record() violationRecord {
+	r := violationRecord{
+		Src:       v.Src.ImportPath,
+		Dst:       v.Dst.ImportPath,
+		Mode:      v.Mode.String(),
+		Config:    v.Config,
+		Chain:     []string{},
+		Error:     v.Err.Error(),
+		Platforms: v.Platforms,
+	}
+	if v.Rule != nil {
+		r.Rule = v.Rule.String()
+	}
+	for _, pkg := range v.Chain {
+		r.Chain = append(r.Chain, pkg.ImportPath)
+	}
+	return r
+}
+
+// String returns the human-readable form of the violation.
+func (v violation) String() string {
+	s := fmt.Sprintf("%q not allowed to import %q (%v)", v.Src.ImportPath, v.Dst.ImportPath, v.Err)
+	if len(v.Platforms) > 0 {
+		s += " [" + strings.Join(v.Platforms, " ") + "]"
+	}
+	return s
+}
+
+// printViolations writes the violations found while checking pkgs to w, in the
+// given format.
+func printViolations(w io.Writer, format string, pkgs []*build.Package, violations []violation) error {
+	switch format {
+	case formatText:
+		for _, v := range violations {
+			fmt.Fprintln(w, v)
+		}
+		return nil
+	case formatJSON:
+		records := []violationRecord{}
+		for _, v := range violations {
+			records = append(records, v.Record())
+		}
+		return writeJSON(w, records)
+	case formatSARIF:
+		root, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, sarifLog(violations, root))
+	case formatXUnit:
+		return writeXUnit(w, pkgs, violations)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("MarshalIndent(%v) failed: %v", value, err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// The following types describe the subset of SARIF 2.1.0 used by godepcop.
+//
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifReport struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool               sarifTool                        `json:"tool"`
+		OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+		Results            []sarifResult                    `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID     string          `json:"ruleId"`
+		Level      string          `json:"level"`
+		Message    sarifMessage    `json:"message"`
+		Locations  []sarifLocation `json:"locations,omitempty"`
+		Properties violationRecord `json:"properties"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+)
+
+const (
+	sarifRuleDeny     = "godepcop/deny"
+	sarifRuleInternal = "godepcop/internal"
+	sarifRuleLayer    = "godepcop/layer"
+
+	// sarifSrcRoot is the base id that locations are relative to.
+	sarifSrcRoot = "%SRCROOT%"
+)
+
+// sarifLog returns a SARIF log of the violations.  Locations under root, which
+// is usually the root of the repository that godepcop is run in, are relative
+// to it; others are absolute file URIs.
+func sarifLog(violations []violation, root string) sarifReport {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name: "godepcop",
+			Rules: []sarifRule{
+				{ID: sarifRuleDeny, ShortDescription: sarifMessage{"Import rejected by a .godepcop deny rule"}},
+				{ID: sarifRuleInternal, ShortDescription: sarifMessage{errGo15Internal.Error()}},
+				{ID: sarifRuleLayer, ShortDescription: sarifMessage{"Import of a package in a higher layer"}},
+			},
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactLocation{
+			sarifSrcRoot: {URI: fileURI(root) + "/"},
+		},
+		Results: []sarifResult{},
+	}
+	for _, v := range violations {
+		result := sarifResult{
+			RuleID:     sarifRuleDeny,
+			Level:      "error",
+			Message:    sarifMessage{v.String()},
+			Properties: v.Record(),
+		}
+		// Point at the config file containing the deciding rule or layers, or at
+		// the importing package for the internal package rule.
+		uri := v.Config
+		switch {
+		case v.Err == errGo15Internal:
+			result.RuleID = sarifRuleInternal
+			uri = v.Src.Dir
+		case v.Rule == nil:
+			result.RuleID = sarifRuleLayer
+		}
+		if uri != "" {
+			result.Locations = []sarifLocation{{sarifPhysicalLocation{sarifLocationOf(uri, root)}}}
+		}
+		run.Results = append(run.Results, result)
+	}
+	return sarifReport{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
+
+// sarifLocationOf returns the artifact location of the file or directory at
+// path, relative to root if path is under it.
+func sarifLocationOf(path, root string) sarifArtifactLocation {
+	if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: sarifSrcRoot}
+	}
+	return sarifArtifactLocation{URI: fileURI(path)}
+}
+
+// fileURI returns the file URI of the absolute path.
+func fileURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// writeXUnit writes an xUnit report with one test suite per checked package.
+// Packages with violations have a single failing test case, which lists all of
+// the violations for that package.
+func writeXUnit(w io.Writer, pkgs []*build.Package, violations []violation) error {
+	const testName = "CheckDependencies"
+	byPkg := make(map[string][]violation)
+	for _, v := range violations {
+		byPkg[v.Src.ImportPath] = append(byPkg[v.Src.ImportPath], v)
+	}
+	var suites xunit.TestSuites
+	for _, pkg := range pkgs {
+		vs := byPkg[pkg.ImportPath]
+		if len(vs) == 0 {
+			suites.Suites = append(suites.Suites, xunit.TestSuite{
+				Name:  pkg.ImportPath,
+				Cases: []xunit.TestCase{{Name: testName, Classname: pkg.ImportPath, Time: "0.00"}},
+				Tests: 1,
+			})
+			continue
+		}
+		var out bytes.Buffer
+		for _, v := range vs {
+			fmt.Fprintln(&out, v)
+		}
+		s := xunit.CreateTestSuiteWithFailure(pkg.ImportPath, testName, "dependency violation", out.String(), 0)
+		suites.Suites = append(suites.Suites, *s)
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("MarshalIndent(%v) failed: %v", suites, err)
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
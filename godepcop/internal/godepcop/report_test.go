@@ -0,0 +1,115 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"go/build"
+	"reflect"
+	"testing"
+
+	"v.io/x/devtools/internal/xunit"
+)
+
+func testViolations() ([]*build.Package, []violation) {
+	a, b, c, d := pkg("a"), pkg("b"), pkg("c"), pkg("d/internal")
+	b.Dir = "/src/b"
+	r := deny("c")
+	errDeny := errors.New(`violates test deny rule "c" in /src/b/.godepcop`)
+	pkgs := []*build.Package{a, b}
+	violations := []violation{
+		{Src: b, Dst: c, Err: errDeny, Mode: modeTest, Rule: &r, Config: "/src/b/.godepcop", Chain: []*build.Package{b, a, c}},
+		{Src: b, Dst: d, Err: errGo15Internal, Mode: modePkg, Chain: []*build.Package{b, d}},
+	}
+	return pkgs, violations
+}
+
+func TestPrintViolationsJSON(t *testing.T) {
+	pkgs, violations := testViolations()
+	var buf bytes.Buffer
+	if err := printViolations(&buf, formatJSON, pkgs, violations); err != nil {
+		t.Fatalf("printViolations failed: %v", err)
+	}
+	var got []violationRecord
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", buf.String(), err)
+	}
+	want := []violationRecord{
+		{Src: "b", Dst: "c", Mode: "test", Rule: `deny="c"`, Config: "/src/b/.godepcop", Chain: []string{"b", "a", "c"}, Error: `violates test deny rule "c" in /src/b/.godepcop`},
+		{Src: "b", Dst: "d/internal", Mode: "pkg", Chain: []string{"b", "d/internal"}, Error: errGo15Internal.Error()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// No violations must still produce a valid empty list.
+	buf.Reset()
+	if err := printViolations(&buf, formatJSON, pkgs, nil); err != nil {
+		t.Fatalf("printViolations failed: %v", err)
+	}
+	if got, want := buf.String(), "[]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrintViolationsSARIF(t *testing.T) {
+	pkgs, violations := testViolations()
+	var buf bytes.Buffer
+	if err := printViolations(&buf, formatSARIF, pkgs, violations); err != nil {
+		t.Fatalf("printViolations failed: %v", err)
+	}
+	var got sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", buf.String(), err)
+	}
+	if len(got.Runs) != 1 || len(got.Runs[0].Results) != 2 {
+		t.Fatalf("got %v, want one run with two results", got)
+	}
+	for _, test := range []struct {
+		root string
+		want []sarifArtifactLocation
+	}{
+		// Locations under the root are relative to it.
+		{"/src", []sarifArtifactLocation{{"b/.godepcop", sarifSrcRoot}, {"b", sarifSrcRoot}}},
+		{"/src/b", []sarifArtifactLocation{{".godepcop", sarifSrcRoot}, {".", sarifSrcRoot}}},
+		// Others are absolute file URIs.
+		{"/other", []sarifArtifactLocation{{"file:///src/b/.godepcop", ""}, {"file:///src/b", ""}}},
+		{"/sr", []sarifArtifactLocation{{"file:///src/b/.godepcop", ""}, {"file:///src/b", ""}}},
+	} {
+		run := sarifLog(violations, test.root).Runs[0]
+		if got, want := run.OriginalURIBaseIDs[sarifSrcRoot].URI, "file://"+test.root+"/"; got != want {
+			t.Errorf("%s: got base %q, want %q", test.root, got, want)
+		}
+		for i, rule := range []string{sarifRuleDeny, sarifRuleInternal} {
+			result := run.Results[i]
+			if result.RuleID != rule || len(result.Locations) != 1 || result.Locations[0].PhysicalLocation.ArtifactLocation != test.want[i] {
+				t.Errorf("%s: result %d got %v, want rule %q at %v", test.root, i, result, rule, test.want[i])
+			}
+		}
+	}
+}
+
+func TestPrintViolationsXUnit(t *testing.T) {
+	pkgs, violations := testViolations()
+	var buf bytes.Buffer
+	if err := printViolations(&buf, formatXUnit, pkgs, violations); err != nil {
+		t.Fatalf("printViolations failed: %v", err)
+	}
+	var got xunit.TestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", buf.String(), err)
+	}
+	if len(got.Suites) != 2 {
+		t.Fatalf("got %d suites, want 2", len(got.Suites))
+	}
+	if s := got.Suites[0]; s.Name != "a" || s.Tests != 1 || s.Failures != 0 {
+		t.Errorf("got suite %v, want passing suite for a", s)
+	}
+	if s := got.Suites[1]; s.Name != "b" || s.Tests != 1 || s.Failures != 1 {
+		t.Errorf("got suite %v, want failing suite for b", s)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// pkgStats describes how much a package drags into the binaries that import
+// it.  Files and Lines count the Go and cgo files of the package itself and of
+// all of its transitive dependencies.
+type pkgStats struct {
+	Package    string `json:"package"`
+	Direct     int    `json:"direct"`
+	Transitive int    `json:"transitive"`
+	Importers  int    `json:"importers"`
+	Files      int    `json:"files"`
+	Lines      int    `json:"lines"`
+	Goroot     int    `json:"goroot"`
+	ThirdParty int    `json:"third_party"`
+	Cgo        int    `json:"cgo"`
+}
+
+// statsColumns holds the names of the stats columns, in the order they are
+// printed.
+var statsColumns = []string{"package", "direct", "transitive", "importers", "files", "lines", "goroot", "third_party", "cgo"}
+
+func (s pkgStats) column(name string) int {
+	switch name {
+	case "direct":
+		return s.Direct
+	case "transitive":
+		return s.Transitive
+	case "importers":
+		return s.Importers
+	case "files":
+		return s.Files
+	case "lines":
+		return s.Lines
+	case "goroot":
+		return s.Goroot
+	case "third_party":
+		return s.ThirdParty
+	case "cgo":
+		return s.Cgo
+	}
+	return 0
+}
+
+func isStatsColumn(name string) bool {
+	for _, column := range statsColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// computeStats returns the stats of each of pkgs.  The importers of each
+// package are counted among the given universe of packages, in the same way as
+// list-importers.  Direct, Transitive, Files and Lines only count $GOROOT
+// packages if opts.IncludeGoroot is set; the Goroot column always counts them.
+func computeStats(pkgs, universe []*build.Package, opts depOpts) ([]pkgStats, error) {
+	importers := make(map[string]int)
+	for _, pkg := range universe {
+		deps := make(map[string]*build.Package)
+		if err := opts.Deps(pkg, deps); err != nil {
+			return nil, err
+		}
+		for path := range deps {
+			importers[path]++
+		}
+	}
+	allOpts := opts
+	allOpts.IncludeGoroot = true
+	lines := make(map[string]int)
+	var stats []pkgStats
+	for _, pkg := range pkgs {
+		s := pkgStats{Package: pkg.ImportPath, Importers: importers[pkg.ImportPath]}
+		direct := allOpts
+		direct.DirectOnly = true
+		directDeps := make(map[string]*build.Package)
+		if err := direct.Deps(pkg, directDeps); err != nil {
+			return nil, err
+		}
+		for _, dep := range directDeps {
+			if opts.IncludeGoroot || !dep.Goroot {
+				s.Direct++
+			}
+		}
+		deps := make(map[string]*build.Package)
+		if err := allOpts.Deps(pkg, deps); err != nil {
+			return nil, err
+		}
+		// The package itself counts towards the files and lines.
+		counted := []*build.Package{pkg}
+		for _, dep := range deps {
+			switch {
+			case dep.Goroot:
+				s.Goroot++
+			case packageCategory(dep) == categoryThirdParty:
+				s.ThirdParty++
+			}
+			if opts.IncludeGoroot || !dep.Goroot {
+				s.Transitive++
+				if len(dep.CgoFiles) > 0 {
+					s.Cgo++
+				}
+				counted = append(counted, dep)
+			}
+		}
+		for _, p := range counted {
+			n, ok := lines[p.ImportPath]
+			if !ok {
+				var err error
+				if n, err = countLines(p); err != nil {
+					return nil, err
+				}
+				lines[p.ImportPath] = n
+			}
+			s.Files += len(p.GoFiles) + len(p.CgoFiles)
+			s.Lines += n
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// countLines returns the number of lines in the Go and cgo files of pkg.
+func countLines(pkg *build.Package) (int, error) {
+	var n int
+	for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles} {
+		for _, file := range files {
+			data, err := ioutil.ReadFile(filepath.Join(pkg.Dir, file))
+			if err != nil {
+				return 0, err
+			}
+			n += bytes.Count(data, []byte("\n"))
+		}
+	}
+	return n, nil
+}
+
+// sortStats sorts stats by the given column; numeric columns are sorted in
+// descending order, and ties are broken by package.
+func sortStats(stats []pkgStats, column string) {
+	sort.Sort(statsSorter{stats, column})
+}
+
+type statsSorter struct {
+	stats  []pkgStats
+	column string
+}
+
+func (s statsSorter) Len() int { return len(s.stats) }
+func (s statsSorter) Less(i, j int) bool {
+	if s.column != "package" {
+		if a, b := s.stats[i].column(s.column), s.stats[j].column(s.column); a != b {
+			return a > b
+		}
+	}
+	return s.stats[i].Package < s.stats[j].Package
+}
+func (s statsSorter) Swap(i, j int) { s.stats[i], s.stats[j] = s.stats[j], s.stats[i] }
+
+// printStats writes stats to w in the given format.
+func printStats(w io.Writer, format string, stats []pkgStats) error {
+	switch format {
+	case formatText:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(statsColumns, "\t"))
+		for _, s := range stats {
+			row := []string{s.Package}
+			for _, column := range statsColumns[1:] {
+				row = append(row, fmt.Sprint(s.column(column)))
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case formatJSON:
+		if stats == nil {
+			stats = []pkgStats{}
+		}
+		return writeJSON(w, stats)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
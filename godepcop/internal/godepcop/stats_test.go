@@ -0,0 +1,93 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godepcop
+
+import (
+	"bytes"
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	var universe []*build.Package
+	for _, path := range []string{"test-a", "test-b", "test-c", "test-c/child"} {
+		pkg, err := importPackage(v + path)
+		if err != nil {
+			t.Fatalf("%v failed: %v", path, err)
+		}
+		universe = append(universe, pkg)
+	}
+	stats, err := computeStats(universe[:3], universe, depOpts{})
+	if err != nil {
+		t.Fatalf("computeStats failed: %v", err)
+	}
+	// Clear the goroot counts, which depend on the version of Go.
+	for i := range stats {
+		if stats[i].Goroot == 0 {
+			t.Errorf("%v: got no goroot packages", stats[i].Package)
+		}
+		stats[i].Goroot = 0
+	}
+	want := []pkgStats{
+		{Package: v + "test-a", Direct: 0, Transitive: 0, Importers: 2, Files: 1, Lines: 11},
+		{Package: v + "test-b", Direct: 1, Transitive: 2, Importers: 0, Files: 3, Lines: 37},
+		{Package: v + "test-c", Direct: 1, Transitive: 1, Importers: 1, Files: 2, Lines: 22},
+	}
+	if got := stats; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sortStats(stats, "lines")
+	var order []string
+	for _, s := range stats {
+		order = append(order, s.Package)
+	}
+	if got, want := order, []string{v + "test-b", v + "test-c", v + "test-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted by lines got %v, want %v", got, want)
+	}
+}
+
+func TestComputeStatsCgo(t *testing.T) {
+	const v = "v.io/x/devtools/godepcop/testdata/"
+	pkg, err := importPackage(v + "test-cgo")
+	if err != nil {
+		t.Fatalf("importPackage failed: %v", err)
+	}
+	universe := []*build.Package{pkg}
+	// Only lib is counted, since os/user is in GOROOT.
+	stats, err := computeStats(universe, universe, depOpts{})
+	if err != nil {
+		t.Fatalf("computeStats failed: %v", err)
+	}
+	if got, want := stats[0].Cgo, 1; got != want {
+		t.Errorf("got %d cgo packages, want %d", got, want)
+	}
+	stats, err = computeStats(universe, universe, depOpts{IncludeGoroot: true})
+	if err != nil {
+		t.Fatalf("computeStats failed: %v", err)
+	}
+	if got, want := stats[0].Cgo, 2; got != want {
+		t.Errorf("got %d cgo packages including goroot, want %d", got, want)
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	stats := []pkgStats{
+		{"a", 1, 2, 3, 4, 5, 6, 7, 8},
+		{"a/long/package", 10, 20, 30, 40, 50, 60, 70, 80},
+	}
+	var buf bytes.Buffer
+	if err := printStats(&buf, formatText, stats); err != nil {
+		t.Fatalf("printStats failed: %v", err)
+	}
+	want := `package         direct  transitive  importers  files  lines  goroot  third_party  cgo
+a               1       2           3          4      5      6       7            8
+a/long/package  10      20          30         40     50     60      70           80
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
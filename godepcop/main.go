@@ -0,0 +1,24 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The following enables go generate to generate the doc.go file.
+//go:generate go run $JIRI_ROOT/release/go/src/v.io/x/lib/cmdline/testdata/gendoc.go .
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/tools/go/analysis/unitchecker"
+	"v.io/x/devtools/godepcop/analysis"
+	"v.io/x/devtools/godepcop/internal/godepcop"
+	"v.io/x/lib/cmdline"
+)
+
+func main() {
+	if godepcop.IsVetTool(os.Args[1:]) {
+		unitchecker.Main(analysis.Analyzer)
+	}
+	cmdline.Main(godepcop.Root)
+}
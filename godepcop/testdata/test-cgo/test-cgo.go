@@ -0,0 +1,7 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testcgo
+
+import _ "v.io/x/devtools/godepcop/testdata/test-cgo/lib"
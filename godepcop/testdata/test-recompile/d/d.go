@@ -0,0 +1,17 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package d imports q, so the external test of q, which imports d, makes the
+// go tool recompile d against the test variant of q.
+package d
+
+import (
+	"v.io/x/devtools/godepcop/testdata/test-a"
+	"v.io/x/devtools/godepcop/testdata/test-recompile/q"
+)
+
+func D() {
+	q.Q()
+	testa.A()
+}
@@ -0,0 +1,15 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package q_test
+
+import (
+	"testing"
+
+	"v.io/x/devtools/godepcop/testdata/test-recompile/d"
+)
+
+func TestQ(t *testing.T) {
+	d.D()
+}
@@ -0,0 +1,369 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const configFileName = ".gologcop"
+
+// config is the contents of a .gologcop file, which selects the methods
+// that must have a log construct.  The rules in the package sections that
+// match a package take precedence over the top-level rules of the file, and
+// the files in the directories closer to a package take precedence over
+// those further up.  Within a set of rules, a method is selected if it
+// matches an include rule, and otherwise ignored if it matches an exclude
+// rule.  Methods that no rule matches are selected.  Redaction rules are
+// cumulative: all of those in the files and package sections that apply to a
+// package are in effect.
+type config struct {
+	XMLName  struct{}        `xml:"gologcop"`
+	Include  []rule          `xml:"include"`
+	Exclude  []rule          `xml:"exclude"`
+	Redact   []redactRule    `xml:"redact"`
+	Packages []packageConfig `xml:"package"`
+	Path     string          `xml:"-"`
+}
+
+// packageConfig holds the rules for the packages matching Path, which is
+// either a package path, or a path followed by "/..." to also match all of
+// the packages below it, or "..." to match all packages.
+type packageConfig struct {
+	Path    string       `xml:"path,attr"`
+	Include []rule       `xml:"include"`
+	Exclude []rule       `xml:"exclude"`
+	Redact  []redactRule `xml:"redact"`
+}
+
+func (p packageConfig) Matches(pkg string) bool {
+	switch {
+	case p.Path == "...":
+		return true
+	case strings.HasSuffix(p.Path, "/..."):
+		prefix := strings.TrimSuffix(p.Path, "/...")
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == p.Path
+}
+
+// rule matches methods by regular expressions, which must match the whole
+// name.  A rule matches a method if all of its expressions do.
+type rule struct {
+	// Interface matches the interfaces, qualified by their package paths,
+	// that the method is part of.  It matches if it matches all of them.
+	Interface string `xml:"interface,attr,omitempty"`
+	// Type matches the receiver type, qualified by its package path, or
+	// the type that the method is promoted to by embedding.
+	Type string `xml:"type,attr,omitempty"`
+	// Method matches the method name.
+	Method string `xml:"method,attr,omitempty"`
+}
+
+func (r rule) String() string {
+	s := []string{}
+	for _, attr := range []struct{ name, value string }{{"interface", r.Interface}, {"type", r.Type}, {"method", r.Method}} {
+		if len(attr.value) > 0 {
+			s = append(s, fmt.Sprintf("%s=%q", attr.name, attr.value))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func (r rule) Validate() error {
+	if len(r.Interface) == 0 && len(r.Type) == 0 && len(r.Method) == 0 {
+		return errEmptyRule
+	}
+	for _, expr := range []string{r.Interface, r.Type, r.Method} {
+		if _, err := wholeRegexp(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the rule matches the method with the given name,
+// qualified receiver type and interfaces.
+func (r rule) Matches(method, typ string, interfaces []string) bool {
+	if len(r.Method) > 0 && !matchesWhole(r.Method, method) {
+		return false
+	}
+	if len(r.Type) > 0 && !matchesWhole(r.Type, typ) {
+		return false
+	}
+	if len(r.Interface) > 0 {
+		if len(interfaces) == 0 {
+			return false
+		}
+		for _, ifc := range interfaces {
+			if !matchesWhole(r.Interface, ifc) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var regexpCache = map[string]*regexp.Regexp{}
+
+// wholeRegexp returns the regular expression that matches whole strings
+// matching expr.
+func wholeRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	regexpCache[expr] = re
+	return re, nil
+}
+
+// matchesWhole returns true if s matches expr as a whole.  Expressions are
+// compiled when the config is parsed, so they are known to be valid.
+func matchesWhole(expr, s string) bool {
+	re, err := wholeRegexp(expr)
+	return err == nil && re.MatchString(s)
+}
+
+var (
+	errEmptyRule = errors.New("at least one of interface, type and method must be specified")
+	errEmptyPath = errors.New("package path must be specified")
+	errNoRules   = errors.New("at least one rule must be specified")
+	errEmptyPkg  = errors.New("at least one include, exclude or redact rule must be specified")
+)
+
+func parseConfig(data []byte) (*config, error) {
+	c := new(config)
+	if err := xml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if len(c.Include) == 0 && len(c.Exclude) == 0 && len(c.Redact) == 0 && len(c.Packages) == 0 {
+		return nil, errNoRules
+	}
+	if err := validateRules(c.Include, c.Exclude, c.Redact); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Packages {
+		switch {
+		case len(p.Path) == 0:
+			return nil, fmt.Errorf("package: %v", errEmptyPath)
+		case len(p.Include) == 0 && len(p.Exclude) == 0 && len(p.Redact) == 0:
+			return nil, fmt.Errorf("package %s: %v", p.Path, errEmptyPkg)
+		}
+		if err := validateRules(p.Include, p.Exclude, p.Redact); err != nil {
+			return nil, fmt.Errorf("package %s: %v", p.Path, err)
+		}
+	}
+	return c, nil
+}
+
+func validateRules(include, exclude []rule, redact []redactRule) error {
+	for _, r := range include {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("include: %v", err)
+		}
+	}
+	for _, r := range exclude {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("exclude: %v", err)
+		}
+	}
+	for _, r := range redact {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("redact: %v", err)
+		}
+	}
+	return nil
+}
+
+var configCache = map[string]*config{}
+
+// loadConfig loads the .gologcop file at the given path, returning nil if
+// there is none.  Configs are cached, so each file is read only once.
+func loadConfig(path string) (*config, error) {
+	if c, ok := configCache[path]; ok {
+		return c, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			configCache[path] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	c, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	c.Path = path
+	configCache[path] = c
+	return c, nil
+}
+
+// configsFor returns the .gologcop files that apply to bpkg, starting with
+// the one in the package directory, and then in successive directories up to
+// the root of the import path.
+func configsFor(bpkg *build.Package) ([]*config, error) {
+	configs := []*config{}
+	dir := bpkg.Dir
+	for depth := strings.Count(bpkg.ImportPath, "/"); depth >= 0; depth-- {
+		c, err := loadConfig(filepath.Join(dir, configFileName))
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			configs = append(configs, c)
+		}
+		dir = filepath.Dir(dir)
+	}
+	return configs, nil
+}
+
+// ruleSet holds the rules of a .gologcop file, or of one of its package
+// sections.
+type ruleSet struct {
+	where            string
+	include, exclude []rule
+}
+
+// ruleSets holds the rule sets that apply to a package, in order of
+// precedence.
+type ruleSets []ruleSet
+
+// ruleSetsFor returns the rule sets in the .gologcop files that apply to
+// bpkg.
+func ruleSetsFor(bpkg *build.Package) (ruleSets, error) {
+	configs, err := configsFor(bpkg)
+	if err != nil {
+		return nil, err
+	}
+	sets := ruleSets{}
+	for _, c := range configs {
+		for _, p := range c.Packages {
+			if p.Matches(bpkg.ImportPath) {
+				sets = append(sets, ruleSet{c.Path + ": package " + p.Path, p.Include, p.Exclude})
+			}
+		}
+		sets = append(sets, ruleSet{c.Path, c.Include, c.Exclude})
+	}
+	return sets, nil
+}
+
+// selects returns true if the rule sets select the method with the given
+// name, qualified receiver type and interfaces.
+func (sets ruleSets) selects(ps *parseState, method, typ string, interfaces []string) bool {
+	for _, set := range sets {
+		for _, r := range set.include {
+			if r.Matches(method, typ, interfaces) {
+				return true
+			}
+		}
+		for _, r := range set.exclude {
+			if r.Matches(method, typ, interfaces) {
+				progressMsg(ps.jirix.Stdout(), "%s.%s: excluded by %s: %v\n", typ, method, set.where, r)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// selectsAny returns true if the rule sets select the named method of any of
+// objs, which are types declared in the package at pkgPath, matching each
+// with the interfaces that it implements via the method.  The types that
+// implement none of interfaces are ignored, and if all are, the method is
+// selected.
+func (sets ruleSets) selectsAny(ps *parseState, method, pkgPath string, objs []*types.TypeName, interfaces []*types.Named) bool {
+	matched := false
+	for _, obj := range objs {
+		ifcs := implementedInterfacesOf([]*types.TypeName{obj}, method, interfaces)
+		if len(ifcs) == 0 {
+			continue
+		}
+		matched = true
+		if sets.selects(ps, method, pkgPath+"."+obj.Name(), ifcs) {
+			return true
+		}
+	}
+	return !matched
+}
+
+// selectMethods returns the methods selected by the .gologcop files that
+// apply to bpkg.  A method is matched with its receiver type, and with the
+// types in bpkg that it is promoted to by embedding, and is selected if it
+// is selected for any of those that implement one of interfaces via it.
+func selectMethods(ps *parseState, bpkg *build.Package, interfaces []*types.Named, methods []funcDeclRef) ([]funcDeclRef, error) {
+	sets, err := ruleSetsFor(bpkg)
+	if err != nil || len(sets) == 0 {
+		return methods, err
+	}
+	selected := []funcDeclRef{}
+	for _, m := range methods {
+		fn, ok := ps.info.Defs[m.Decl.Name].(*types.Func)
+		if !ok {
+			selected = append(selected, m)
+			continue
+		}
+		if sets.selectsAny(ps, fn.Name(), bpkg.ImportPath, methodTypes(fn), interfaces) {
+			selected = append(selected, m)
+		}
+	}
+	return selected, nil
+}
+
+// selectPromotions returns the promotions selected by the .gologcop files
+// that apply to bpkg, with the implementation types that the promoted method
+// is selected for, which are matched as by selectMethods.
+func selectPromotions(ps *parseState, bpkg *build.Package, interfaces []*types.Named, promoted map[token.Pos]promotion) (map[token.Pos]promotion, error) {
+	sets, err := ruleSetsFor(bpkg)
+	if err != nil || len(sets) == 0 {
+		return promoted, err
+	}
+	selected := map[token.Pos]promotion{}
+	for pos, p := range promoted {
+		objs := []*types.TypeName{}
+		for _, obj := range p.Types {
+			if sets.selectsAny(ps, p.Method.Name(), bpkg.ImportPath, []*types.TypeName{obj}, interfaces) {
+				objs = append(objs, obj)
+			}
+		}
+		if len(objs) > 0 {
+			selected[pos] = promotion{Types: objs, Method: p.Method}
+		}
+	}
+	return selected, nil
+}
+
+// redactionsFor returns the redaction rules in the .gologcop files that
+// apply to bpkg.
+func redactionsFor(bpkg *build.Package) (redactRules, error) {
+	configs, err := configsFor(bpkg)
+	if err != nil {
+		return nil, err
+	}
+	var rules redactRules
+	for _, c := range configs {
+		for _, p := range c.Packages {
+			if p.Matches(bpkg.ImportPath) {
+				rules = append(rules, p.Redact...)
+			}
+		}
+		rules = append(rules, c.Redact...)
+	}
+	return rules, nil
+}
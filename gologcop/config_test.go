@@ -0,0 +1,109 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		xml  string
+		want *config
+		err  string
+	}{
+		{
+			xml: `<gologcop>
+  <include method="Send|Recv" type=".*\.Stream"/>
+  <exclude interface=".*"/>
+  <redact name="password"/>
+  <package path="a/...">
+    <exclude method="Close"/>
+    <redact type="a\.Key" tag="log:redact"/>
+  </package>
+</gologcop>`,
+			want: &config{
+				Include: []rule{{Type: `.*\.Stream`, Method: "Send|Recv"}},
+				Exclude: []rule{{Interface: ".*"}},
+				Redact:  []redactRule{{Name: "password"}},
+				Packages: []packageConfig{{
+					Path:    "a/...",
+					Exclude: []rule{{Method: "Close"}},
+					Redact:  []redactRule{{Type: `a\.Key`, Tag: "log:redact"}},
+				}},
+			},
+		},
+		{xml: `<gologcop></gologcop>`, err: errNoRules.Error()},
+		{xml: `<gologcop><exclude/></gologcop>`, err: "exclude: " + errEmptyRule.Error()},
+		{xml: `<gologcop><include method="("/></gologcop>`, err: "include: error parsing regexp"},
+		{xml: `<gologcop><redact/></gologcop>`, err: "redact: " + errEmptyRedact.Error()},
+		{xml: `<gologcop><redact tag=":redact"/></gologcop>`, err: "redact: tag \":redact\" has no key"},
+		{xml: `<gologcop><package><exclude method="A"/></package></gologcop>`, err: "package: " + errEmptyPath.Error()},
+		{xml: `<gologcop><package path="a"/></gologcop>`, err: "package a: " + errEmptyPkg.Error()},
+		{xml: `<gologdog><exclude method="A"/></gologdog>`, err: "expected element type <gologcop>"},
+	}
+	for i, test := range tests {
+		got, err := parseConfig([]byte(test.xml))
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%d: got error %v, want %q", i, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %#v, want %#v", i, got, test.want)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	ifcs := []string{"a.Stream", "b.Stream"}
+	tests := []struct {
+		r    rule
+		want bool
+	}{
+		{rule{Method: "Send"}, true},
+		{rule{Method: "Se"}, false},
+		{rule{Method: "Send|Recv"}, true},
+		{rule{Type: `p\.T`}, true},
+		{rule{Type: "T"}, false},
+		{rule{Interface: `.*\.Stream`}, true},
+		{rule{Interface: `a\.Stream`}, false},
+		{rule{Interface: `.*`, Method: "Recv"}, false},
+	}
+	for _, test := range tests {
+		if got := test.r.Matches("Send", "p.T", ifcs); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.r, got, test.want)
+		}
+	}
+	if (rule{Interface: ".*"}).Matches("Send", "p.T", nil) {
+		t.Errorf("interface rule matched a method that is not part of an interface")
+	}
+}
+
+func TestConfigSelect(t *testing.T) {
+	pkg := path.Join(testPackagePrefix, "config", "test1")
+	_, methods := doTest(t, []string{pkg})
+	got := []string{}
+	for m := range methods {
+		got = append(got, receiverTypeName(m.Decl)+"."+m.Decl.Name.Name)
+	}
+	sort.Strings(got)
+	// Type1.Method2 is excluded by a package section, Stream by type and
+	// Type2.ReturnsSomething is excluded by interface, but included again
+	// by the config in the package directory.
+	want := []string{"Type1.Method1", "Type2.ReturnsSomething"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -12,13 +12,103 @@ When checking, it ensures that all implementations in <packages> of all exported
 interfaces declared in packages passed to the -interface flag have an
 appropriate logging construct.
 
+Methods that a type gets by embedding another type are checked where they
+are declared.  If that is in another package, the log call must be added
+there, or to a wrapper method on the embedding type; check reports such
+methods, but inject leaves them alone.  The methods of a generic type are
+checked if it implements an interface for some instantiation, i.e. if its
+method signatures match where they don't refer to its type parameters.
+
 When injecting or removing, it modifies the source code to inject or remove such
 logging constructs.
 
-LIMITATIONS:
-
-Removal will not automatically remove the package import for the call to be
-removed.
+By default, the logging construct is a call to the apilog package, as
+configured by the -call and -import flags.  Other constructs are described by
+a template file passed to the -template flag.
+
+TEMPLATES:
+
+A template file holds Go text/template definitions.  It must define an
+"inject" template, which renders the statements to inject at the beginning of
+each method, and it may define:
+
+  "import" - the import declaration required by the statements, in the form
+             accepted by -import, e.g. "log/slog".
+  "match"  - a regular expression that recognises an existing construct.  It
+             is matched against the leading statements of each method,
+             printed one per line.  Without it, an existing construct must be
+             identical to the one that inject would add.
+
+The "inject" and "match" templates are executed with the following fields:
+
+  .Pkg      - the package name of the import
+  .Receiver - the name of the receiver type
+  .Method   - the name of the method
+  .Context  - the name of the *context.T or context.Context parameter, if any
+  .Params   - the parameters, excluding the context
+  .Results  - the results
+
+Each parameter and result has the fields .Name, .Verb and .Value, where .Verb
+and .Value are empty if the value is not printable; results are logged via
+their addresses.  .Redacted is set if a redact rule makes the value
+sensitive, in which case .Value is empty.  .Params.Format and .Params.Values
+return the format string and the comma-separated values that apilog would log.  The "regexp" function
+quotes its argument for use in a regular expression.  The injected statements
+are followed by the usual "gologcop: DO NOT EDIT" comment.
+
+For example, the following template logs calls via log/slog:
+
+  {{define "import"}}"log/slog"{{end}}
+  {{define "inject"}}slog.Info("{{.Receiver}}.{{.Method}}"{{range .Params}}{{if .Value}}, "{{.Name}}", {{.Value}}{{end}}{{end}}){{end}}
+  {{define "match"}}^slog\.Info\("{{regexp .Receiver}}\.{{regexp .Method}}"{{end}}
+
+CONFIGURATION:
+
+Methods can be excluded from check and inject by .gologcop files, which apply
+to the packages in their directory and below, e.g. to skip the Send and Recv
+methods of all streams in a repository rather than marking each one with a
+nologcall comment.  A .gologcop file holds include, exclude and redact
+rules, and package sections with rules for the packages that match their path:
+
+  <gologcop>
+    <exclude type=".*Stream" method="Send|Recv"/>
+    <exclude interface="v\.io/v23/rpc\.ServerCall"/>
+    <package path="v.io/x/ref/services/...">
+      <include method="Send"/>
+    </package>
+  </gologcop>
+
+Each include and exclude rule has regular expressions that must match the
+whole of the method name (method), the receiver type (type) or every
+interface that the method is part of (interface); types and interfaces are
+qualified by their package paths.  A method that is promoted to other types
+by embedding is matched as a method of each of the types that implement the
+interfaces via it, and is checked unless it is skipped for all of them.  A
+rule matches if all of its expressions do.  The package sections that match
+a package take precedence over the top-level rules of their file, and files
+closer to the package take precedence over those further up.  Within a set
+of rules, a method that matches an include rule is checked, and otherwise a
+method that matches an exclude rule is skipped.  Methods that no rule
+matches are checked.
+
+Redact rules describe sensitive values, which are replaced by a "<redacted>"
+placeholder in the injected constructs, and which check reports as leaks if
+an existing construct logs them.  A redact rule matches the name of a
+parameter, result, variable or field (name) or its type, qualified by its
+package path (type), by regular expressions that must match the whole name,
+and struct fields by a tag key, optionally followed by a colon and one of the
+comma-separated options of its value (tag).  A rule matches if all of its
+attributes do, e.g.:
+
+  <redact name="password|secret"/>
+  <redact type="v\.io/v23/security\.PrivateKey"/>
+  <redact tag="log:redact"/>
+
+Pointers to, and slices of, a sensitive type are sensitive, as are structs
+with a sensitive field.  All of the redact rules in the files and package
+sections that apply to a package are in effect.
+
+Removal also removes the imports that are no longer used as a result.
 
 Usage:
    gologcop [flags] <command>
@@ -52,6 +142,22 @@ Gologcop check - Check for log statements in public API implementations
 
 Check for log statements in public API implementations.
 
+With -json, the methods that fail the check are printed as a JSON array, sorted
+by position.  Each element has the following fields:
+
+  position   - the file:line:col of the method
+  method     - the method name, qualified by its receiver type
+  interfaces - the interfaces that the method is part of
+  kind       - "not-exists" if the method has no log construct,
+               "invalid" if its log construct is invalid, or "leak" if its
+               log construct logs a value that a redact rule makes sensitive
+  message    - the reason that the method fails the check
+  fix        - the insertions that inject would make for the method, each
+               with its position, byte offset and text; the insertion of
+               the import is repeated for every method in a file, and
+               leaks and promoted methods, which inject leaves alone, have
+               none
+
 Usage:
    gologcop check [flags] <packages>
 
@@ -65,6 +171,13 @@ The gologcop check flags are:
    Import path for the injected call.
  -interface=
    Comma-separated list of interface packages (required).
+ -json=false
+   Print the methods that fail the check as JSON, along with the text that
+   inject would add to fix them.
+ -template=
+   Template file describing the statements to inject and how to recognise
+   existing ones. Overrides --call and --import; see TEMPLATES in the gologcop
+   help.
 
  -color=true
    Use color to format output.
@@ -101,6 +214,10 @@ The gologcop inject flags are:
    Import path for the injected call.
  -interface=
    Comma-separated list of interface packages (required).
+ -template=
+   Template file describing the statements to inject and how to recognise
+   existing ones. Overrides --call and --import; see TEMPLATES in the gologcop
+   help.
 
  -color=true
    Use color to format output.
@@ -116,9 +233,10 @@ The gologcop inject flags are:
 
 Gologcop remove - Remove log statements
 
-Remove log statements. Note that remove modifies <packages> in-place.  It is a
-good idea to commit changes to version control before running this tool so you
-can see the diff or revert the changes.
+Remove log statements, along with the imports that are no longer used. Note that
+remove modifies <packages> in-place.  It is a good idea to commit changes to
+version control before running this tool so you can see the diff or revert the
+changes, or to run it with --diff-only first.
 
 Usage:
    gologcop remove [flags] <packages>
@@ -128,12 +246,16 @@ Usage:
 The gologcop remove flags are:
  -call=apilog.LogCall
    The function call to be removed. Note, that the package selector must be
-   included. No attempt is made to remove the import declaration if the package
-   is no longer used as a result of the removal.
+   included. Imports that are no longer used as a result of the removal are
+   removed too.
  -diff-only=false
    Show changes that would be made without actually making them.
  -gofmt=true
    Automatically run gofmt on the modified files.
+ -template=
+   Template file describing the statements to inject and how to recognise
+   existing ones. Overrides --call and --import; see TEMPLATES in the gologcop
+   help.
 
  -color=true
    Use color to format output.
@@ -5,9 +5,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/build"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"go/types"
@@ -21,6 +23,7 @@ import (
 	"strings"
 	"unicode"
 
+	"golang.org/x/tools/go/ast/astutil"
 	"v.io/jiri"
 	"v.io/jiri/collect"
 	"v.io/x/devtools/internal/goutil"
@@ -34,6 +37,8 @@ const (
 
 	v23ContextPackage  = "v.io/v23/context"
 	v23ContextTypeName = "T"
+	stdContextPackage  = "context"
+	stdContextTypeName = "Context"
 )
 
 var (
@@ -46,6 +51,9 @@ var (
 	injectPackage string
 	// the call to be injected, without the package name.
 	injectCall string
+	// the template describing the log construct, if any, which replaces
+	// the apilog call built from injectPackage and injectCall.
+	logTmpl *logTemplate
 
 	// the package and call to be removed
 	removePackage, removeCall string
@@ -61,6 +69,9 @@ type parseState struct {
 	info     *types.Info
 	packages map[string]*types.Package // keyed by the package path name.
 	asts     map[string][]*ast.File    // keyed by the package path name
+	// bodies holds the paths of the packages whose function bodies are
+	// type checked, so that all uses of their imports are known.
+	bodies map[string]bool
 }
 
 func newState(jirix *jiri.X) *parseState {
@@ -69,6 +80,7 @@ func newState(jirix *jiri.X) *parseState {
 		fset:     token.NewFileSet(),
 		packages: make(map[string]*types.Package),
 		asts:     make(map[string][]*ast.File),
+		bodies:   make(map[string]bool),
 		config: &types.Config{
 			IgnoreFuncBodies: true,
 		},
@@ -76,6 +88,10 @@ func newState(jirix *jiri.X) *parseState {
 			Types: make(map[ast.Expr]types.TypeAndValue),
 			Defs:  make(map[*ast.Ident]types.Object),
 			Uses:  make(map[*ast.Ident]types.Object),
+
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+
+			Implicits: make(map[ast.Node]types.Object),
 		},
 	}
 	ps.config.Importer = ps
@@ -123,9 +139,6 @@ func (ps *parseState) parseAndTypeCheckPackage(bpkg *build.Package) ([]*ast.File
 		return asts, tpkg, nil
 	}
 
-	tpkg := types.NewPackage(bpkg.ImportPath, bpkg.Name)
-	checker := types.NewChecker(ps.config, ps.fset, tpkg, ps.info)
-
 	// Parse the files in this package.
 	asts := []*ast.File{}
 	dir := bpkg.Dir
@@ -137,9 +150,38 @@ func (ps *parseState) parseAndTypeCheckPackage(bpkg *build.Package) ([]*ast.File
 		}
 		asts = append(asts, a)
 	}
-	if err := checker.Files(asts); err != nil {
+
+	tpkg := types.NewPackage(bpkg.ImportPath, bpkg.Name)
+	config := ps.config
+	checkBodies := ps.bodies[bpkg.ImportPath]
+	var declErr error
+	if checkBodies {
+		// Errors in function bodies, such as calls that don't match the
+		// signature of the log call, don't prevent the uses of imports
+		// from being recorded, so they are only reported, as are the
+		// soft errors, such as unused imports, that checking the bodies
+		// brings up.  Any other error is returned.
+		bodies := funcBodies(asts)
+		c := *ps.config
+		c.IgnoreFuncBodies = false
+		c.Error = func(err error) {
+			if terr, ok := err.(types.Error); ok && (terr.Soft || bodies.contain(terr.Pos)) {
+				progressMsg(ps.jirix.Stdout(), "ignoring type error: %v\n", err)
+				return
+			}
+			if declErr == nil {
+				declErr = err
+			}
+		}
+		config = &c
+	}
+	checker := types.NewChecker(config, ps.fset, tpkg, ps.info)
+	if err := checker.Files(asts); err != nil && !checkBodies {
 		return nil, nil, err
 	}
+	if declErr != nil {
+		return nil, nil, declErr
+	}
 	// make sure that type checking is complete at this stage. It should
 	// always be so, so this is really an 'assertion' that it is.
 	if !tpkg.Complete() {
@@ -150,6 +192,42 @@ func (ps *parseState) parseAndTypeCheckPackage(bpkg *build.Package) ([]*ast.File
 	return asts, tpkg, nil
 }
 
+// posRanges is a list of ranges of positions.
+type posRanges [][2]token.Pos
+
+// contain returns true if pos is within one of the ranges.
+func (r posRanges) contain(pos token.Pos) bool {
+	for _, rng := range r {
+		if rng[0] <= pos && pos < rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// funcBodies returns the ranges of the bodies of the functions, and function
+// literals, in files.
+func funcBodies(files []*ast.File) posRanges {
+	var bodies posRanges
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var body *ast.BlockStmt
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				body = n.Body
+			case *ast.FuncLit:
+				body = n.Body
+			}
+			if body != nil {
+				bodies = append(bodies, [2]token.Pos{body.Pos(), body.End()})
+				return false
+			}
+			return true
+		})
+	}
+	return bodies
+}
+
 // importPkgs will expand the supplied list of  packages using go list
 // (so v.io/v23/... can be used as an interface package spec for example) and
 // then import those packages.
@@ -181,26 +259,52 @@ func importPkgs(jirix *jiri.X, goFlags, packageSpec []string) (ifcs []*build.Pac
 // function as sets.
 var exists = struct{}{}
 
-func initInjectorFlags() error {
-	parts := strings.FieldsFunc(injectCallImportFlag, unicode.IsSpace)
-	var err error
+// parseImportSpec parses an import declaration of the form accepted by the
+// -import flag, i.e. an optionally quoted path, optionally preceded by a tag.
+func parseImportSpec(spec string) (tag, importPath string, err error) {
+	parts := strings.FieldsFunc(spec, unicode.IsSpace)
 	switch len(parts) {
 	case 1:
-		injectImportTag = ""
-		injectImportPath, err = strconv.Unquote(injectCallImportFlag)
+		importPath, err = strconv.Unquote(spec)
 		if err != nil {
-			injectImportPath = injectCallImportFlag
+			importPath = spec
 		}
-		injectPackage = path.Base(injectImportPath)
+		return "", importPath, nil
 	case 2:
-		injectImportTag = parts[0]
-		injectImportPath, err = strconv.Unquote(parts[1])
+		importPath, err = strconv.Unquote(parts[1])
 		if err != nil {
-			injectImportPath = parts[1]
+			importPath = parts[1]
 		}
-		injectPackage = injectImportTag
+		return parts[0], importPath, nil
+	}
+	return "", "", fmt.Errorf("%q doesn't look like an import declaration", spec)
+}
+
+// setInjectImport sets the import tag, path and package from spec.
+func setInjectImport(spec string) error {
+	tag, importPath, err := parseImportSpec(spec)
+	if err != nil {
+		return err
+	}
+	injectImportTag, injectImportPath = tag, importPath
+	switch {
+	case len(tag) > 0:
+		injectPackage = tag
+	case len(importPath) > 0:
+		injectPackage = path.Base(importPath)
 	default:
-		return fmt.Errorf("%q doesn't look like an import declaration", injectCallImportFlag)
+		injectPackage = ""
+	}
+	return nil
+}
+
+func initInjectorFlags() error {
+	if len(templateFlag) > 0 {
+		return initTemplate(templateFlag)
+	}
+	logTmpl = nil
+	if err := setInjectImport(injectCallImportFlag); err != nil {
+		return err
 	}
 	injectCall = injectCallFlag
 	return nil
@@ -228,7 +332,11 @@ func runInjector(jirix *jiri.X, goFlags, interfaceList, implementationList []str
 	progressMsg(jirix.Stdout(), "%v expands to %d implementation packages\n", implementationList, len(impls))
 
 	ps := newState(jirix)
+	for _, impl := range impls {
+		ps.bodies[impl.ImportPath] = true
+	}
 	checkFailed := []string{}
+	results := []checkResult{}
 
 	printHeader(jirix.Stdout(), "Parsing and Type Checking Interface Packages")
 
@@ -248,22 +356,47 @@ func runInjector(jirix *jiri.X, goFlags, interfaceList, implementationList []str
 		if err != nil {
 			return fmt.Errorf("failed to parse+type check: %s: %s", impl.ImportPath, err)
 		}
+		redact, err := redactionsFor(impl)
+		if err != nil {
+			return err
+		}
 
 		// Now find the methods that implement those public interfaces.
-		methods := findMethodsImplementing(jirix, ps.fset, tpkg, publicInterfaces)
+		methods, promoted := findMethodsImplementing(jirix, ps.fset, tpkg, publicInterfaces)
 
 		// and their positions in the files.
-		methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, methods)
+		methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, redact, methods)
+		if err != nil {
+			return err
+		}
+		// then drop those excluded by .gologcop files,
+		if methodPositions, err = selectMethods(ps, impl, publicInterfaces, methodPositions); err != nil {
+			return err
+		}
+		if promoted, err = selectPromotions(ps, impl, publicInterfaces, promoted); err != nil {
+			return err
+		}
+		// then check to see if those methods already have logging statements
+		// that don't leak sensitive values.
+		needsInjection := checkMethods(ps.info, redact, methodPositions)
+		// Methods promoted from other packages need their log statements
+		// there, which is only reported.
+		promotedFailures, err := checkPromotedMethods(ps, redact, promoted)
 		if err != nil {
 			return err
 		}
-		// then check to see if those methods already have logging statements.
-		needsInjection := checkMethods(methodPositions)
+		for m, err := range promotedFailures {
+			needsInjection[m] = err
+		}
 
 		if checkOnly {
 			if len(needsInjection) > 0 {
-				printHeader(jirix.Stdout(), "Check Results")
-				reportResults(jirix, ps.fset, needsInjection)
+				if jsonFlag {
+					results = append(results, checkResults(ps.fset, ps.info, publicInterfaces, needsInjection)...)
+				} else {
+					printHeader(jirix.Stdout(), "Check Results")
+					reportResults(jirix, ps.fset, needsInjection)
+				}
 				checkFailed = append(checkFailed, impl.ImportPath)
 			}
 		} else {
@@ -273,6 +406,15 @@ func runInjector(jirix *jiri.X, goFlags, interfaceList, implementationList []str
 		}
 	}
 
+	if checkOnly && jsonFlag {
+		if err := writeJSON(jirix.Stdout(), results); err != nil {
+			return err
+		}
+		if len(checkFailed) > 0 {
+			os.Exit(1)
+		}
+	}
+
 	if checkOnly && len(checkFailed) > 0 {
 		for _, p := range checkFailed {
 			fmt.Fprintf(jirix.Stdout(), "check failed for: %s\n", p)
@@ -284,6 +426,10 @@ func runInjector(jirix *jiri.X, goFlags, interfaceList, implementationList []str
 }
 
 func initRemoverFlags() error {
+	if len(templateFlag) > 0 {
+		return initTemplate(templateFlag)
+	}
+	logTmpl = nil
 	parts := strings.Split(removeCallFlag, ".")
 	switch len(parts) {
 	case 2:
@@ -308,6 +454,9 @@ func runRemover(jirix *jiri.X, goFlags, implementationList []string) error {
 	}
 
 	ps := newState(jirix)
+	for _, impl := range impls {
+		ps.bodies[impl.ImportPath] = true
+	}
 
 	printHeader(jirix.Stdout(), "Package Summary")
 	progressMsg(jirix.Stdout(), "%v expands to %d implementation packages\n", implementationList, len(impls))
@@ -317,13 +466,17 @@ func runRemover(jirix *jiri.X, goFlags, implementationList []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to parse+type check: %s: %s", impl.ImportPath, err)
 		}
+		redact, err := redactionsFor(impl)
+		if err != nil {
+			return err
+		}
 		methods := findMethods(jirix, ps.fset, tpkg)
-		methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, methods)
+		methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, redact, methods)
 		if err != nil {
 			return err
 		}
 		needsRemoval := findRemovals(methodPositions)
-		if err := remove(jirix, ps.fset, needsRemoval); err != nil {
+		if err := remove(jirix, ps.fset, ps.info, needsRemoval); err != nil {
 			return fmt.Errorf("removal failed for: %s: %s", impl.ImportPath, err)
 		}
 	}
@@ -331,20 +484,32 @@ func runRemover(jirix *jiri.X, goFlags, implementationList []string) error {
 }
 
 // funcDeclRef stores a reference to a function declaration, paired
-// with the file containing it.
+// with the file containing it and the log statement to inject into it.
 type funcDeclRef struct {
 	Decl    *ast.FuncDecl
 	File    *ast.File
-	LogCall string
+	LogCall logStatement
+}
+
+// logStatement describes the log construct of a method.
+type logStatement struct {
+	// Text is the text to be injected after the opening brace.
+	Text string
+	// NumStmts is the number of statements in Text.
+	NumStmts int
+	// Match is the regular expression that recognises an existing
+	// construct when a template is in use.
+	Match string
 }
 
 // methodSetVisibleThroughInterfaces returns intersection of all
 // exported method names implemented by t and the union of all method
 // names declared by interfaces.
-func methodSetVisibleThroughInterfaces(t types.Type, interfaces []*types.Interface) map[string]struct{} {
+func methodSetVisibleThroughInterfaces(t types.Type, interfaces []*types.Named) map[string]struct{} {
 	set := map[string]struct{}{}
-	for _, ifc := range interfaces {
-		if types.Implements(t, ifc) || types.Implements(types.NewPointer(t), ifc) {
+	for _, named := range interfaces {
+		ifc := named.Underlying().(*types.Interface)
+		if types.Implements(t, ifc) || types.Implements(types.NewPointer(t), ifc) || implementsGeneric(t, ifc) {
 			// t implements ifc, so add all the public
 			// method names of ifc to set.
 			for i := 0; i < ifc.NumMethods(); i++ {
@@ -357,7 +522,87 @@ func methodSetVisibleThroughInterfaces(t types.Type, interfaces []*types.Interfa
 	return set
 }
 
+// implementsGeneric returns true if t is a generic type that implements ifc
+// for some instantiation, i.e. if it has all of the methods of ifc with
+// signatures that are identical where they don't refer to the type
+// parameters of t.  This makes the methods that are checked independent of
+// how t is instantiated.
+func implementsGeneric(t types.Type, ifc *types.Interface) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.TypeParams().Len() == 0 {
+		return false
+	}
+	for i := 0; i < ifc.NumMethods(); i++ {
+		m := ifc.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, m.Pkg(), m.Name())
+		fn, ok := obj.(*types.Func)
+		if !ok || !identicalModuloTypeParams(fn.Type(), m.Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// identicalModuloTypeParams returns true if x and y are identical, with the
+// type parameters in x matching any type.
+func identicalModuloTypeParams(x, y types.Type) bool {
+	switch x := x.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		y, ok := y.(*types.Pointer)
+		return ok && identicalModuloTypeParams(x.Elem(), y.Elem())
+	case *types.Slice:
+		y, ok := y.(*types.Slice)
+		return ok && identicalModuloTypeParams(x.Elem(), y.Elem())
+	case *types.Array:
+		y, ok := y.(*types.Array)
+		return ok && x.Len() == y.Len() && identicalModuloTypeParams(x.Elem(), y.Elem())
+	case *types.Map:
+		y, ok := y.(*types.Map)
+		return ok && identicalModuloTypeParams(x.Key(), y.Key()) && identicalModuloTypeParams(x.Elem(), y.Elem())
+	case *types.Chan:
+		y, ok := y.(*types.Chan)
+		return ok && x.Dir() == y.Dir() && identicalModuloTypeParams(x.Elem(), y.Elem())
+	case *types.Signature:
+		y, ok := y.(*types.Signature)
+		return ok && x.Variadic() == y.Variadic() && identicalTuplesModuloTypeParams(x.Params(), y.Params()) && identicalTuplesModuloTypeParams(x.Results(), y.Results())
+	case *types.Named:
+		y, ok := y.(*types.Named)
+		if !ok || x.Origin() != y.Origin() || x.TypeArgs().Len() != y.TypeArgs().Len() {
+			return false
+		}
+		for i := 0; i < x.TypeArgs().Len(); i++ {
+			if !identicalModuloTypeParams(x.TypeArgs().At(i), y.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Identical(x, y)
+}
+
+func identicalTuplesModuloTypeParams(x, y *types.Tuple) bool {
+	if x.Len() != y.Len() {
+		return false
+	}
+	for i := 0; i < x.Len(); i++ {
+		if !identicalModuloTypeParams(x.At(i).Type(), y.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
 func hasV23Context(info *types.Info, parameters *ast.FieldList) (*ast.FieldList, string) {
+	return contextParam(info, parameters, false)
+}
+
+// contextParam returns parameters without the first context parameter, and
+// the name of that parameter, or "nil" if there is none or it is unnamed.
+// A context parameter is a *context.T from v.io/v23/context, or, if stdlib
+// is set, a context.Context.
+func contextParam(info *types.Info, parameters *ast.FieldList, stdlib bool) (*ast.FieldList, string) {
 	if !useContextFlag {
 		return parameters, ""
 	}
@@ -366,29 +611,85 @@ func hasV23Context(info *types.Info, parameters *ast.FieldList) (*ast.FieldList,
 	}
 	filtered := *parameters
 	for i, field := range filtered.List {
-		typ := info.TypeOf(field.Type)
-		ptr, ok := typ.(*types.Pointer)
-		if !ok {
+		if !isContextType(info.TypeOf(field.Type), stdlib) {
 			continue
 		}
+		filtered.List = append(append([]*ast.Field{}, filtered.List[:i]...), filtered.List[i+1:]...)
+		jirixname := "nil"
+		if len(field.Names) > 0 && field.Names[0].Name != "_" {
+			jirixname = field.Names[0].Name
+		}
+		return &filtered, jirixname
+	}
+	return &filtered, "nil"
+}
+
+func isContextType(typ types.Type, stdlib bool) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
 		named, ok := ptr.Elem().(*types.Named)
 		if !ok {
-			continue
+			return false
 		}
 		name := named.Obj()
-		if name.Pkg().Path() == v23ContextPackage && name.Name() == v23ContextTypeName {
-			filtered.List = append(filtered.List[:i], filtered.List[i+1:]...)
-			jirixname := "nil"
-			if len(field.Names) > 0 && field.Names[0].Name != "_" {
-				jirixname = field.Names[0].Name
-			}
-			return &filtered, jirixname
+		return name.Pkg() != nil && name.Pkg().Path() == v23ContextPackage && name.Name() == v23ContextTypeName
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || !stdlib {
+		return false
+	}
+	name := named.Obj()
+	return name.Pkg() != nil && name.Pkg().Path() == stdContextPackage && name.Name() == stdContextTypeName
+}
+
+// logArg describes a parameter or result to be logged.
+type logArg struct {
+	Name string
+	// Verb is the format verb for the value, or empty if the value is not
+	// printable.
+	Verb string
+	// Value is the expression that is logged, or empty if the value is not
+	// printable.
+	Value    string
+	Variadic bool
+	// Redacted is set if the value is sensitive, in which case Verb is the
+	// redaction placeholder and Value is empty.
+	Redacted bool
+}
+
+func (a logArg) format() string {
+	if a.Variadic {
+		return a.Name + "...=" + a.Verb
+	}
+	return a.Name + "=" + a.Verb
+}
+
+type logArgs []logArg
+
+// Format returns the format string for args, e.g. "a=%v,b=".
+func (args logArgs) Format() string {
+	format := []string{}
+	for _, a := range args {
+		format = append(format, a.format())
+	}
+	return strings.Join(format, ",")
+}
+
+// Values returns the comma-separated printable values of args.
+func (args logArgs) Values() string {
+	return strings.Join(args.values(), ", ")
+}
+
+func (args logArgs) values() []string {
+	values := []string{}
+	for _, a := range args {
+		if len(a.Value) > 0 {
+			values = append(values, a.Value)
 		}
 	}
-	return &filtered, "nil"
+	return values
 }
 
-func genFmt(info *types.Info, fields *ast.FieldList, indirect bool) ([]string, []string, error) {
+func genFmt(info *types.Info, redact redactRules, fields *ast.FieldList, indirect bool) (logArgs, error) {
 
 	fmtForBasicType := func(typ *types.Basic) string {
 		if typ.Kind() == types.String {
@@ -399,10 +700,9 @@ func genFmt(info *types.Info, fields *ast.FieldList, indirect bool) ([]string, [
 	}
 
 	if fields == nil {
-		return nil, nil, nil
+		return nil, nil
 	}
-	format := []string{}
-	args := []string{}
+	args := logArgs{}
 	for _, param := range fields.List {
 		typ := info.TypeOf(param.Type)
 		var f string
@@ -425,7 +725,7 @@ func genFmt(info *types.Info, fields *ast.FieldList, indirect bool) ([]string, [
 			}
 		case nil:
 			if _, ok := param.Type.(*ast.Ellipsis); !ok {
-				return nil, nil, fmt.Errorf("failed to locate type for %v", param.Names)
+				return nil, fmt.Errorf("failed to locate type for %v", param.Names)
 			}
 			// We'll print out the ellipsis args as a slice of whatever type it is.
 			f = "%v"
@@ -434,39 +734,49 @@ func genFmt(info *types.Info, fields *ast.FieldList, indirect bool) ([]string, [
 		}
 		for _, n := range param.Names {
 			if n.Name != "_" && len(n.Name) > 0 {
-				if printable {
-					if ellipsis {
-						format = append(format, n.Name+"...="+f)
-					} else {
-						format = append(format, n.Name+"="+f)
-					}
-					name := n.Name
+				arg := logArg{Name: n.Name, Variadic: ellipsis}
+				if redact.sensitive(n.Name, paramType(info, param), "") != nil {
+					arg.Verb = redactedValue
+					arg.Redacted = true
+				} else if printable {
+					arg.Verb = f
+					arg.Value = n.Name
 					if indirect {
-						name = "&" + name
+						arg.Value = "&" + n.Name
 					}
-					args = append(args, name)
-				} else {
-					format = append(format, n.Name+"=")
 				}
+				args = append(args, arg)
 			}
 		}
 	}
-	return format, args, nil
+	return args, nil
 }
 
-func genCall(info *types.Info, params, results *ast.FieldList) (string, error) {
+// paramType returns the type of param, or for a variadic parameter that has
+// no type recorded, the type of its elements.
+func paramType(info *types.Info, param *ast.Field) types.Type {
+	if typ := info.TypeOf(param.Type); typ != nil {
+		return typ
+	}
+	if ellipsis, ok := param.Type.(*ast.Ellipsis); ok {
+		return info.TypeOf(ellipsis.Elt)
+	}
+	return nil
+}
+
+func genCall(info *types.Info, redact redactRules, params, results *ast.FieldList) (string, error) {
 	params, contextPar := hasV23Context(info, params)
 	noargs := fmt.Sprintf("\n\tdefer %s.%s(%s)(%s) %s", injectPackage, injectCall, contextPar, contextPar, logCallComment)
 	if info == nil {
 		return noargs, nil
 	}
 
-	argFormat, printableArgs, err := genFmt(info, params, false)
+	argFormat, err := genFmt(info, redact, params, false)
 	if err != nil {
 		return "", err
 	}
 
-	resFormat, printableResults, err := genFmt(info, results, true)
+	resFormat, err := genFmt(info, redact, results, true)
 	if err != nil {
 		return "", err
 	}
@@ -475,17 +785,20 @@ func genCall(info *types.Info, params, results *ast.FieldList) (string, error) {
 		return noargs, nil
 	}
 
-	formatArgs := func(format, parameters []string) string {
-		if len(format) > 0 {
-			formatStr := strings.TrimSpace(strings.Join(format, ","))
-			parametersStr := strings.Join(parameters, ",")
-			return fmt.Sprintf("\"%s\", %s", formatStr, parametersStr)
+	formatArgs := func(args logArgs) string {
+		if len(args) > 0 {
+			formatStr := strings.TrimSpace(args.Format())
+			values := args.values()
+			if len(values) == 0 {
+				return fmt.Sprintf("\"%s\"", formatStr)
+			}
+			return fmt.Sprintf("\"%s\", %s", formatStr, strings.Join(values, ","))
 		}
 		return "\"\""
 	}
 
-	pars := formatArgs(argFormat, printableArgs)
-	res := formatArgs(resFormat, printableResults)
+	pars := formatArgs(argFormat)
+	res := formatArgs(resFormat)
 
 	contextParArg, contextParRes := contextPar, contextPar
 	if len(contextPar) > 0 {
@@ -503,35 +816,52 @@ func genCall(info *types.Info, params, results *ast.FieldList) (string, error) {
 // functionDeclarationsAtPositions returns references to function
 // declarations in packages where the position of the identifier token
 // representing the name of the function is in positions.
-func functionDeclarationsAtPositions(fset *token.FileSet, files []*ast.File, info *types.Info, positions map[token.Pos]struct{}) ([]funcDeclRef, error) {
+func functionDeclarationsAtPositions(fset *token.FileSet, files []*ast.File, info *types.Info, redact redactRules, positions map[token.Pos]struct{}) ([]funcDeclRef, error) {
 	result := []funcDeclRef{}
 	for _, file := range files {
 		for _, decl := range file.Decls {
 			if decl, ok := decl.(*ast.FuncDecl); ok {
-				call, err := genCall(info, decl.Type.Params, decl.Type.Results)
-				if err != nil {
-					pos := fset.Position(decl.Pos())
-					return nil, fmt.Errorf("%s:%d: %v", pos.Filename, pos.Line, err)
-				}
 				// for each function declaration in packages:
 				//
 				// it's important not to use decl.Pos() here
 				// as it gives us the position of the "func"
 				// token, whereas positions has collected
 				// the locations of method name tokens:
-				if _, ok := positions[decl.Name.Pos()]; ok {
-					result = append(result, funcDeclRef{decl, file, call})
+				if _, ok := positions[decl.Name.Pos()]; !ok {
+					continue
 				}
+				stmt, err := genLogStatement(info, redact, decl)
+				if err != nil {
+					pos := fset.Position(decl.Pos())
+					return nil, fmt.Errorf("%s:%d: %v", pos.Filename, pos.Line, err)
+				}
+				result = append(result, funcDeclRef{Decl: decl, File: file, LogCall: stmt})
 			}
 		}
 	}
 	return result, nil
 }
 
+// genLogStatement returns the log construct to be injected into decl, as
+// described by the template if one is in use, or the apilog call otherwise.
+// The values that redact makes sensitive are replaced by a placeholder.
+func genLogStatement(info *types.Info, redact redactRules, decl *ast.FuncDecl) (logStatement, error) {
+	if logTmpl != nil {
+		return logTmpl.statement(info, redact, decl)
+	}
+	call, err := genCall(info, redact, decl.Type.Params, decl.Type.Results)
+	if err != nil {
+		return logStatement{}, err
+	}
+	return logStatement{Text: call, NumStmts: 1}, nil
+}
+
 // findMethodsImplementing searches the specified packages and returns
 // a list of function declarations that are implementations for
-// the specified interfaces.
-func findMethodsImplementing(jirix *jiri.X, fset *token.FileSet, tpkg *types.Package, interfaces []*types.Interface) map[token.Pos]struct{} {
+// the specified interfaces.  Methods that are promoted from embedded
+// types declared in other packages are returned separately, since their
+// declarations are not in tpkg.
+func findMethodsImplementing(jirix *jiri.X, fset *token.FileSet, tpkg *types.Package, interfaces []*types.Named) (map[token.Pos]struct{}, map[token.Pos]promotion) {
 	// positions will hold the set of Pos values of methods
 	// that should be logged.  Each element will be the position of
 	// the identifier token representing the method name of such
@@ -540,12 +870,16 @@ func findMethodsImplementing(jirix *jiri.X, fset *token.FileSet, tpkg *types.Pac
 	// objects to ast.FuncDecl objects, so we then look into AST
 	// declarations and find everything that has a matching position.
 	positions := map[token.Pos]struct{}{}
+	promoted := map[token.Pos]promotion{}
 
 	printHeader(jirix.Stdout(), "Methods Implementing Public Interfaces in %s", tpkg.Path())
 
 	scope := tpkg.Scope()
 	for _, child := range scope.Names() {
-		object := scope.Lookup(child)
+		object, ok := scope.Lookup(child).(*types.TypeName)
+		if !ok {
+			continue
+		}
 		typ := object.Type()
 		// ignore interfaces as they have no method implementations
 		if types.IsInterface(typ) {
@@ -559,28 +893,41 @@ func findMethodsImplementing(jirix *jiri.X, fset *token.FileSet, tpkg *types.Pac
 		// we care about, we can just ignore it.
 		if len(apiMethodSet) > 0 {
 			// find all the methods explicitly declared or implicitly
-			// inherited through embedding on type t or *t.
-			methodSet := types.NewMethodSet(typ)
-			if methodSet.Len() == 0 {
-				methodSet = types.NewMethodSet(types.NewPointer(typ))
-			}
+			// inherited through embedding on type t or *t; the method
+			// set of *t includes those of t.
+			methodSet := types.NewMethodSet(types.NewPointer(typ))
 			for i := 0; i < methodSet.Len(); i++ {
 				method := methodSet.At(i)
 				fn := method.Obj().(*types.Func)
 				// t may have a method that is not declared in any of
 				// the interfaces we care about. No need to log that.
-				if _, ok := apiMethodSet[fn.Name()]; ok {
-					if fn.Pos() == 0 {
-						// Embedded functions show up with a zero pos.
-						continue
-					}
-					progressMsg(jirix.Stdout(), "%s.%s: %s\n", tpkg.Path(), fn.Name(), fset.Position(fn.Pos()))
-					positions[fn.Pos()] = exists
+				if _, ok := apiMethodSet[fn.Name()]; !ok {
+					continue
+				}
+				switch {
+				case fn.Pos() == 0:
+					// Methods of types loaded from export data have
+					// no positions.
+					progressMsg(jirix.Stdout(), "%s.%s: skipped, no source\n", tpkg.Path(), fn.Name())
+					continue
+				case types.IsInterface(fn.Type().(*types.Signature).Recv().Type()):
+					// Methods of embedded interfaces have no
+					// declaration to log in.
+					progressMsg(jirix.Stdout(), "%s.%s.%s: skipped, promoted from an embedded interface\n", tpkg.Path(), object.Name(), fn.Name())
+					continue
+				case fn.Pkg() != tpkg:
+					progressMsg(jirix.Stdout(), "%s.%s.%s: promoted from %s\n", tpkg.Path(), object.Name(), fn.Name(), fset.Position(fn.Pos()))
+					p := promoted[fn.Pos()]
+					p.Types, p.Method = append(p.Types, object), fn
+					promoted[fn.Pos()] = p
+					continue
 				}
+				progressMsg(jirix.Stdout(), "%s.%s: %s\n", tpkg.Path(), fn.Name(), fset.Position(fn.Pos()))
+				positions[fn.Pos()] = exists
 			}
 		}
 	}
-	return positions
+	return positions, promoted
 }
 
 func findMethodsInScope(jirix *jiri.X, fset *token.FileSet, positions map[token.Pos]struct{}, scope *types.Scope) {
@@ -623,13 +970,6 @@ func insertAt(offset int, text string) patch {
 	}
 }
 
-func removeRange(from, to int) patch {
-	return patch{
-		Offset:     from,
-		NextOffset: to,
-	}
-}
-
 func (p patchSorter) Len() int {
 	return len(p)
 }
@@ -653,6 +993,10 @@ func countOverlap(a, b string) (i int) {
 // import declaration to the package to be injected, and adds one if it does not
 // already.
 func ensureImportLogPackage(fset *token.FileSet, file *ast.File) (patch, bool) {
+	if len(injectImportPath) == 0 {
+		// The injected statements need no import.
+		return patch{}, false
+	}
 	maxOverlap := 0
 	var candidate token.Pos
 
@@ -741,7 +1085,7 @@ func methodBeginsWithNoLogComment(m funcDeclRef) bool {
 func findRemovals(methods []funcDeclRef) map[funcDeclRef]error {
 	result := map[funcDeclRef]error{}
 	for _, m := range methods {
-		if err := validateLogStatement(m.Decl, removePackage, removeCall); err == nil {
+		if err := validateMethod(m, removePackage, removeCall); err == nil {
 			result[m] = nil
 		}
 	}
@@ -750,10 +1094,10 @@ func findRemovals(methods []funcDeclRef) map[funcDeclRef]error {
 
 // checkMethods checks all items in methods and returns the subset
 // of them that do not have valid log statements.
-func checkMethods(methods []funcDeclRef) map[funcDeclRef]error {
+func checkMethods(info *types.Info, redact redactRules, methods []funcDeclRef) map[funcDeclRef]error {
 	result := map[funcDeclRef]error{}
 	for _, m := range methods {
-		if err := checkMethod(m); err != nil {
+		if err := checkMethod(info, redact, m); err != nil {
 			result[m] = err
 		}
 	}
@@ -761,12 +1105,26 @@ func checkMethods(methods []funcDeclRef) map[funcDeclRef]error {
 }
 
 // checkMethod checks that method includes an acceptable logging
-// construct before any other non-whitespace or non-comment token.
-func checkMethod(method funcDeclRef) error {
-	if err := validateLogStatement(method.Decl, injectPackage, injectCall); err != nil && !methodBeginsWithNoLogComment(method) {
+// construct before any other non-whitespace or non-comment token, and
+// that the construct doesn't log a value that redact makes sensitive.
+func checkMethod(info *types.Info, redact redactRules, method funcDeclRef) error {
+	if err := validateMethod(method, injectPackage, injectCall); err != nil {
+		if methodBeginsWithNoLogComment(method) {
+			return nil
+		}
 		return err
 	}
-	return nil
+	return redact.leak(info, method.Decl, method.LogCall.NumStmts)
+}
+
+// validateMethod returns an error if method does not begin with a
+// construct matching the template, if one is in use, or with a valid
+// <pkg>.<name> call otherwise.
+func validateMethod(method funcDeclRef, pkg, name string) error {
+	if logTmpl != nil {
+		return logTmpl.validate(method)
+	}
+	return validateLogStatement(method.Decl, pkg, name)
 }
 
 // gofmt runs "gofmt -w files...".
@@ -778,25 +1136,10 @@ func gofmt(jirix *jiri.X, verbose bool, files []string) error {
 }
 
 // writeFiles writes out files modified by the patch sets supplied to it.
-func writeFiles(jirix *jiri.X, fset *token.FileSet, files map[*ast.File][]patch) (e error) {
-	filesToFormat := []string{}
-
-	// Write out files in a fixed order so that other tools/tests can count on the
-	// diff output.
-	filenames := []string{}
-	asts := map[string]*ast.File{}
-	for file, _ := range files {
+func writeFiles(jirix *jiri.X, fset *token.FileSet, files map[*ast.File][]patch) error {
+	sources := map[string][]byte{}
+	for file, patches := range files {
 		filename := fset.Position(file.Pos()).Filename
-		filenames = append(filenames, filename)
-		asts[filename] = file
-	}
-	sort.Strings(filenames)
-
-	s := jirix.NewSeq()
-	for _, filename := range filenames {
-		file := asts[filename]
-		patches := files[file]
-		filesToFormat = append(filesToFormat, filename)
 		sort.Sort(patchSorter(patches))
 		src, err := ioutil.ReadFile(filename)
 		if err != nil {
@@ -810,6 +1153,28 @@ func writeFiles(jirix *jiri.X, fset *token.FileSet, files map[*ast.File][]patch)
 			beginOffset = patch.NextOffset
 		}
 		patchedSrc = append(patchedSrc, src[beginOffset:]...)
+		sources[filename] = patchedSrc
+	}
+	return writeSources(jirix, sources)
+}
+
+// writeSources writes out the modified sources of files, keyed by their
+// filenames, or shows the diffs for them if --diff-only is set.
+func writeSources(jirix *jiri.X, sources map[string][]byte) (e error) {
+	filesToFormat := []string{}
+
+	// Write out files in a fixed order so that other tools/tests can count on the
+	// diff output.
+	filenames := []string{}
+	for filename, _ := range sources {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	s := jirix.NewSeq()
+	for _, filename := range filenames {
+		src := sources[filename]
+		filesToFormat = append(filesToFormat, filename)
 		if diffOnlyFlag {
 			tmpDir, err := s.TempDir("", "")
 			if err != nil {
@@ -817,14 +1182,14 @@ func writeFiles(jirix *jiri.X, fset *token.FileSet, files map[*ast.File][]patch)
 			}
 			tmpFilename := filepath.Join(tmpDir, "gologcop-"+filepath.Base(filename))
 			defer collect.Error(func() error { return jirix.NewSeq().RemoveAll(tmpDir).Done() }, &e)
-			if err := s.WriteFile(tmpFilename, patchedSrc, os.FileMode(0644)).Done(); err != nil {
+			if err := s.WriteFile(tmpFilename, src, os.FileMode(0644)).Done(); err != nil {
 				return err
 			}
 			progressMsg(jirix.Stdout(), "Diffing %s with %s\n", filename, tmpFilename)
 			gofmt(jirix, false, []string{tmpFilename})
 			s.Verbose(false).Capture(jirix.Stdout(), jirix.Stderr()).Last("diff", filename, tmpFilename)
 		} else {
-			s.WriteFile(filename, patchedSrc, 644).Done()
+			s.WriteFile(filename, src, 644).Done()
 		}
 	}
 	if diffOnlyFlag {
@@ -833,9 +1198,11 @@ func writeFiles(jirix *jiri.X, fset *token.FileSet, files map[*ast.File][]patch)
 	return gofmt(jirix, jirix.Verbose(), filesToFormat)
 }
 
-// remove removes a log call at the beginning of each method in methods.
-func remove(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) error {
-	files := map[*ast.File][]patch{}
+// remove removes the log construct at the beginning of each method in
+// methods.  The statements of the construct and their trailing comments are
+// removed from the syntax tree, along with the imports that are no longer
+// used as a result, and the modified files are then printed anew.
+func remove(jirix *jiri.X, fset *token.FileSet, info *types.Info, methods map[funcDeclRef]error) error {
 	comments := map[*ast.File]ast.CommentMap{}
 	for fdRef, _ := range methods {
 		file := fdRef.File
@@ -844,13 +1211,14 @@ func remove(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) e
 		}
 	}
 
-	// endAt returns the position of the next statement, comment or function,
-	// i.e. the end of the block of code to be removed.
-	endAt := func(fn *ast.FuncDecl, cm ast.CommentMap) int {
+	// endAt returns the position of the next statement, comment or function
+	// after the first n statements, i.e. the end of the block of code to be
+	// removed.
+	endAt := func(fn *ast.FuncDecl, n int, cm ast.CommentMap) token.Pos {
 		endpos := fn.Body.Rbrace
-		stmt := fn.Body.List[0]
-		if len(fn.Body.List) > 1 {
-			nextStmt := fn.Body.List[1]
+		stmt := fn.Body.List[n-1]
+		if len(fn.Body.List) > n {
+			nextStmt := fn.Body.List[n]
 			endpos = nextStmt.Pos()
 			if cg := cm.Filter(nextStmt).Comments(); len(cg) > 0 {
 				if len(cg[0].List) > 0 {
@@ -871,30 +1239,167 @@ func remove(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) e
 				}
 			}
 		}
-		return fset.Position(endpos).Offset
+		return endpos
 	}
 
+	removals := map[*ast.File][]removal{}
 	for m, _ := range methods {
-		file := m.File
 		stmts := m.Decl.Body.List
-		if len(stmts) == 0 {
+		n := m.LogCall.NumStmts
+		if len(stmts) < n || n == 0 {
 			return fmt.Errorf("no statements found for %s", m.Decl.Name)
 		}
-		// The first statement should be the call we want to remove.
-		start := fset.Position(stmts[0].Pos()).Offset
-		end := endAt(m.Decl, comments[m.File])
-		files[file] = append(files[file], removeRange(start, end))
+		// The first n statements should be the construct we want to remove.
+		removals[m.File] = append(removals[m.File], removal{
+			method: m.Decl,
+			stmts:  n,
+			start:  stmts[0].Pos(),
+			end:    endAt(m.Decl, n, comments[m.File]),
+		})
+	}
+
+	sources := map[string][]byte{}
+	for file, rs := range removals {
+		src, err := removeFromFile(fset, info, file, rs)
+		if err != nil {
+			return err
+		}
+		sources[fset.Position(file.Pos()).Filename] = src
 	}
-	return writeFiles(jirix, fset, files)
+	return writeSources(jirix, sources)
+}
+
+// removal describes the leading statements of a method to be removed, which
+// together with their trailing comments span the source range [start, end).
+type removal struct {
+	method     *ast.FuncDecl
+	stmts      int
+	start, end token.Pos
+}
+
+type removalSorter []removal
+
+func (r removalSorter) Len() int {
+	return len(r)
+}
+
+func (r removalSorter) Less(i, j int) bool {
+	return r[i].start < r[j].start
+}
+
+func (r removalSorter) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// removeFromFile applies removals to file, deletes the imports that were
+// only used by the removed statements, and returns the resulting source.
+func removeFromFile(fset *token.FileSet, info *types.Info, file *ast.File, removals []removal) ([]byte, error) {
+	sort.Sort(removalSorter(removals))
+
+	// The imports used by the removed statements are candidates for
+	// deletion.
+	candidates := map[types.Object]bool{}
+	for _, r := range removals {
+		for _, stmt := range r.method.Body.List[:r.stmts] {
+			for obj, _ := range importsUsed(info, stmt) {
+				candidates[obj] = true
+			}
+		}
+		r.method.Body.List = r.method.Body.List[r.stmts:]
+	}
+
+	// Drop the comments within the removed ranges.
+	inRemoval := func(pos token.Pos) bool {
+		for _, r := range removals {
+			if r.start <= pos && pos < r.end {
+				return true
+			}
+		}
+		return false
+	}
+	groups := []*ast.CommentGroup{}
+	for _, cg := range file.Comments {
+		list := []*ast.Comment{}
+		for _, c := range cg.List {
+			if !inRemoval(c.Pos()) {
+				list = append(list, c)
+			}
+		}
+		if len(list) > 0 {
+			cg.List = list
+			groups = append(groups, cg)
+		}
+	}
+	file.Comments = groups
+
+	// Join the lines of each removed range so that the printer doesn't leave
+	// blank lines in its place.  Ranges are joined from the last to the
+	// first, so that the line numbers of the earlier ones stay valid.
+	tfile := fset.File(file.Pos())
+	for i := len(removals) - 1; i >= 0; i-- {
+		first, last := tfile.Line(removals[i].start), tfile.Line(removals[i].end)
+		for line := first; line < last; line++ {
+			tfile.MergeLine(first)
+		}
+	}
+
+	used := importsUsed(info, file)
+	for _, spec := range file.Imports {
+		obj := importObject(info, spec)
+		if obj == nil || !candidates[obj] || used[obj] {
+			continue
+		}
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		astutil.DeleteNamedImport(fset, file, name, path)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importsUsed returns the names of the imported packages referred to
+// within node.
+func importsUsed(info *types.Info, node ast.Node) map[types.Object]bool {
+	used := map[types.Object]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj, ok := info.Uses[ident].(*types.PkgName); ok {
+				used[obj] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// importObject returns the package name declared by spec.
+func importObject(info *types.Info, spec *ast.ImportSpec) types.Object {
+	if spec.Name != nil {
+		return info.Defs[spec.Name]
+	}
+	return info.Implicits[spec]
 }
 
 // inject injects a log call at the beginning of each method in methods.
 func inject(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) error {
 	// Warn the user for methods that already have something at
 	// their beginning that looks like a logging construct, but it
-	// is invalid for some reason.
+	// is invalid for some reason.  Constructs that leak sensitive
+	// values, and methods promoted from other packages, have to be
+	// fixed by hand, so nothing is injected.
 	for m, err := range methods {
-		if _, ok := err.(*errInvalid); ok {
+		switch err.(type) {
+		case *errInvalid, *errLeak, *errPromoted:
 			method := m.Decl
 			position := fset.Position(method.Pos())
 			methodName := method.Name.Name
@@ -903,17 +1408,13 @@ func inject(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) e
 	}
 
 	files := map[*ast.File][]patch{}
-	for m, _ := range methods {
-		text := m.LogCall
-		// Catch the case where the function body is on the same line - e.g. func() {}
-		// so that we make sure we add a newline to the comment to push the right brace
-		// onto the next line.
-		if fset.Position(m.Decl.Body.Lbrace).Line == fset.Position(m.Decl.Body.Rbrace).Line {
-			text += "\n"
-		}
-		delta := insertAt(fset.Position(m.Decl.Body.Lbrace).Offset+1, text)
+	for m, err := range methods {
+		switch err.(type) {
+		case *errLeak, *errPromoted:
+			continue
+		}
 		file := m.File
-		files[file] = append(files[file], delta)
+		files[file] = append(files[file], injection(fset, m))
 	}
 
 	for file, deltas := range files {
@@ -924,6 +1425,19 @@ func inject(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) e
 	return writeFiles(jirix, fset, files)
 }
 
+// injection returns the patch that injects the log call at the
+// beginning of method.
+func injection(fset *token.FileSet, method funcDeclRef) patch {
+	text := method.LogCall.Text
+	// Catch the case where the function body is on the same line - e.g. func() {}
+	// so that we make sure we add a newline to the comment to push the right brace
+	// onto the next line.
+	if fset.Position(method.Decl.Body.Lbrace).Line == fset.Position(method.Decl.Body.Rbrace).Line {
+		text += "\n"
+	}
+	return insertAt(fset.Position(method.Decl.Body.Lbrace).Offset+1, text)
+}
+
 // reportResults prints out the validation results from checkMethods
 // in a human-readable form.
 func reportResults(jirix *jiri.X, fset *token.FileSet, methods map[funcDeclRef]error) {
@@ -1032,7 +1546,7 @@ func progressMsg(out io.Writer, format string, args ...interface{}) {
 
 // findPublicInterfaces returns all the public interfaces defined in the
 // supplied packages.
-func findPublicInterfaces(jirix *jiri.X, ifcs []*types.Package) (interfaces []*types.Interface) {
+func findPublicInterfaces(jirix *jiri.X, ifcs []*types.Package) (interfaces []*types.Named) {
 	for _, ifc := range ifcs {
 		printHeader(jirix.Stdout(), "Public Interfaces for %s", ifc.Path())
 		scope := ifc.Scope()
@@ -1040,12 +1554,13 @@ func findPublicInterfaces(jirix *jiri.X, ifcs []*types.Package) (interfaces []*t
 			object := scope.Lookup(child)
 			typ := object.Type()
 
-			if object.Exported() && types.IsInterface(typ) {
+			named, ok := typ.(*types.Named)
+			if object.Exported() && ok && types.IsInterface(typ) {
 				ifcType := typ.Underlying().(*types.Interface)
 
 				if !ifcType.Empty() {
 					progressMsg(jirix.Stdout(), "%s.%s\n", ifc.Path(), object.Name())
-					interfaces = append(interfaces, ifcType)
+					interfaces = append(interfaces, named)
 				}
 			}
 		}
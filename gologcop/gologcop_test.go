@@ -7,14 +7,21 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"go/ast"
 	"go/build"
+	"go/importer"
+	"go/parser"
 	"go/token"
 	"go/types"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -96,6 +103,51 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestRemoveImports(t *testing.T) {
+	fake, cleanup := jiritest.NewFakeJiriRoot(t)
+	defer cleanup()
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout})
+	if _, err := configureDefaultBuildConfig(fake.X, []string{"testpackage"}); err != nil {
+		t.Fatal(err)
+	}
+	diffOnlyFlag = true
+	if err := runRemover(fake.X, skipProfiles, []string{path.Join(testPackagePrefix, "remove", "test1")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `10,11d9
+< 
+< 	"v.io/x/ref/lib/apilog"
+17d14
+< 	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+12d11
+< 	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT`
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRemoveTypeErrors(t *testing.T) {
+	fake, cleanup := jiritest.NewFakeJiriRoot(t)
+	defer cleanup()
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout})
+	if _, err := configureDefaultBuildConfig(fake.X, []string{"testpackage"}); err != nil {
+		t.Fatal(err)
+	}
+	diffOnlyFlag = true
+	if err := runRemover(fake.X, skipProfiles, []string{path.Join(testPackagePrefix, "typeerrors", "test1")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "< \tdefer apilog.LogCall()()"; !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %q", got, want)
+	}
+	err := runRemover(fake.X, skipProfiles, []string{path.Join(testPackagePrefix, "typeerrors", "test2")})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse+type check") {
+		t.Errorf("got %v, want a type check error", err)
+	}
+}
+
 func TestInject(t *testing.T) {
 	savedContextFlag := useContextFlag
 	defer func() {
@@ -234,6 +286,14 @@ func configureDefaultBuildConfig(jirix *jiri.X, tags []string) (cleanup func(),
 }
 
 func doTest(t *testing.T, packages []string) (*token.FileSet, map[funcDeclRef]error) {
+	ps, _, methods := doCheck(t, packages)
+	return ps.fset, methods
+}
+
+// doCheck checks the given packages against the interfaces in the iface
+// test package, and returns the parse state, the interfaces and the
+// methods that fail the check.
+func doCheck(t *testing.T, packages []string) (*parseState, []*types.Named, map[funcDeclRef]error) {
 	fake, cleanup := jiritest.NewFakeJiriRoot(t)
 	defer cleanup()
 	if _, err := configureDefaultBuildConfig(fake.X, []string{"testpackage"}); err != nil {
@@ -258,6 +318,7 @@ func doTest(t *testing.T, packages []string) (*token.FileSet, map[funcDeclRef]er
 	}
 
 	ps := newState(fake.X)
+	ps.bodies[impls[0].ImportPath] = true
 
 	ifc := ifcs[0]
 	_, ifcpkg, err := ps.parseAndTypeCheckPackage(ifc)
@@ -276,13 +337,342 @@ func doTest(t *testing.T, packages []string) (*token.FileSet, map[funcDeclRef]er
 		t.Fatal(err)
 	}
 
-	methods := findMethodsImplementing(fake.X, ps.fset, tpkg, interfaces)
+	methods, promoted := findMethodsImplementing(fake.X, ps.fset, tpkg, interfaces)
 	if len(methods) == 0 {
 		t.Fatalf("Log injector could not find any methods implementing the test interfaces in %v", impls)
 	}
-	methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, methods)
+	redact, err := redactionsFor(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methodPositions, err := functionDeclarationsAtPositions(ps.fset, asts, ps.info, redact, methods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if methodPositions, err = selectMethods(ps, impl, interfaces, methodPositions); err != nil {
+		t.Fatal(err)
+	}
+	if promoted, err = selectPromotions(ps, impl, interfaces, promoted); err != nil {
+		t.Fatal(err)
+	}
+	failures := checkMethods(ps.info, redact, methodPositions)
+	promotedFailures, err := checkPromotedMethods(ps, redact, promoted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for m, err := range promotedFailures {
+		failures[m] = err
+	}
+	return ps, interfaces, failures
+}
+
+func withTemplate(t *testing.T, filename string) func() {
+	saved := templateFlag
+	templateFlag = filepath.Join("testdata", filename)
+	return func() {
+		templateFlag = saved
+		logTmpl = nil
+	}
+}
+
+func TestInjectTemplate(t *testing.T) {
+	defer withTemplate(t, "slog.tmpl")()
+	testInject(t, "iface", "template", 1)
+}
+
+func TestCheckTemplate(t *testing.T) {
+	defer withTemplate(t, "slog.tmpl")()
+	pkg := path.Join(testPackagePrefix, "template", "test2")
+	fset, methods := doTest(t, []string{pkg})
+	got := map[string]string{}
+	for m, err := range methods {
+		name := receiverTypeName(m.Decl) + "." + m.Decl.Name.Name
+		switch err.(type) {
+		case *errInvalid:
+			got[name] = "invalid"
+		case *errNotExists:
+			got[name] = "missing"
+		default:
+			t.Errorf("%v: unexpected error: %v", fset.Position(m.Decl.Pos()), err)
+		}
+	}
+	want := map[string]string{
+		"Type2.Method1": "invalid",
+		"Type2.Method2": "missing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRemoveTemplate(t *testing.T) {
+	defer withTemplate(t, "slog.tmpl")()
+	fake, cleanup := jiritest.NewFakeJiriRoot(t)
+	defer cleanup()
+	var stdout bytes.Buffer
+	fake.X.Context = tool.NewContext(tool.ContextOpts{Stdout: &stdout})
+	if _, err := configureDefaultBuildConfig(fake.X, []string{"testpackage"}); err != nil {
+		t.Fatal(err)
+	}
+	diffOnlyFlag = true
+	if err := runRemover(fake.X, skipProfiles, []string{path.Join(testPackagePrefix, "template", "test2")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `14d13
+< 	slog.Info("Type1.Method1") // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+18d16
+< 	slog.Info("Type1.Method2", "a", a, "extra", true)`
+	if got := strings.TrimSpace(stdout.String()); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTemplateStatements(t *testing.T) {
+	const src = `package p
+
+import "context"
+
+type T struct{}
+
+func (*T) Method(ctx context.Context, name string, n int) (err error) {
+	span := trace.Start(ctx, "T.Method")
+	defer span.End()
+	return nil
+}
+
+func (T) Other(x []int) {
+	span := trace.Start(nil, "T.Other")
+	return
+}
+`
+	dir, err := ioutil.TempDir("", "gologcop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmplFile := filepath.Join(dir, "trace.tmpl")
+	tmpl := `{{define "import"}}"example.com/trace"{{end}}
+{{define "inject"}}span := trace.Start({{or .Context "nil"}}, "{{.Receiver}}.{{.Method}}")
+defer span.End(){{end}}`
+	if err := ioutil.WriteFile(tmplFile, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer withTemplate(t, "")()
+	templateFlag = tmplFile
+	if err := initInjectorFlags(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := injectImportPath, "example.com/trace"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	config := &types.Config{IgnoreFuncBodies: true, Importer: importer.Default()}
+	if _, err := config.Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"\n\tspan := trace.Start(ctx, \"T.Method\") " + logCallComment + "\n\tdefer span.End()", true},
+		{"\n\tspan := trace.Start(nil, \"T.Other\") " + logCallComment + "\n\tdefer span.End()", false},
+	}
+	for i, decl := range file.Decls[2:] {
+		decl := decl.(*ast.FuncDecl)
+		stmt, err := logTmpl.statement(info, nil, decl)
+		if err != nil {
+			t.Errorf("%s: %v", decl.Name, err)
+			continue
+		}
+		if got, want := stmt.Text, tests[i].text; got != want {
+			t.Errorf("%s: got %q, want %q", decl.Name, got, want)
+		}
+		if got, want := stmt.NumStmts, 2; got != want {
+			t.Errorf("%s: got %d, want %d", decl.Name, got, want)
+		}
+		err = logTmpl.validate(funcDeclRef{Decl: decl, File: file, LogCall: stmt})
+		if got, want := err == nil, tests[i].valid; got != want {
+			t.Errorf("%s: got %v, want valid %v", decl.Name, err, want)
+		}
+	}
+}
+
+func TestTemplateWithoutImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gologcop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmplFile := filepath.Join(dir, "print.tmpl")
+	tmpl := `{{define "inject"}}println("{{.Receiver}}.{{.Method}}"){{end}}`
+	if err := ioutil.WriteFile(tmplFile, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer withTemplate(t, "")()
+	// Set the import of the apilog call, which the template must clear.
+	templateFlag = ""
+	if err := initInjectorFlags(); err != nil {
+		t.Fatal(err)
+	}
+	templateFlag = tmplFile
+	if err := initInjectorFlags(); err != nil {
+		t.Fatal(err)
+	}
+	if injectImportTag != "" || injectImportPath != "" || injectPackage != "" {
+		t.Errorf("got import %q %q and package %q, want none", injectImportTag, injectImportPath, injectPackage)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", "package p\n\ntype T struct{}\n\nfunc (T) Method() {}\n", parser.ParseComments)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return ps.fset, checkMethods(methodPositions)
+	if p, hasChanges := ensureImportLogPackage(fset, file); hasChanges {
+		t.Errorf("got import %q, want none", p.Text)
+	}
+	stmt, err := logTmpl.statement(nil, nil, file.Decls[1].(*ast.FuncDecl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stmt.Text, "\n\tprintln(\"T.Method\") "+logCallComment; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckEmbedded(t *testing.T) {
+	base := testPackagePrefix + "/embed/base.Base"
+	tests := []struct {
+		pkg  string
+		want []string
+		// promoted is the start of the message for the methods promoted
+		// from base.
+		promoted string
+	}{
+		{"test1", []string{
+			"Generic.Method1",
+			"Generic.Method2",
+			"Generic.ReturnsSomething",
+			"Type1.Method1 in base.go",
+			"Type2.Method2",
+			"inner.Method1",
+		}, "Type1.Method1 is promoted from " + base + ": "},
+		// test2 has a .gologcop file that excludes Excluded, and all
+		// of the Method2 methods.
+		{"test2", []string{
+			"Type1,Type3.Method1 in base.go",
+			"Type4.Method1",
+		}, "Type1.Method1, Type3.Method1 are promoted from " + base + ": "},
+	}
+	for _, test := range tests {
+		fset, methods := doTest(t, []string{path.Join(testPackagePrefix, "embed", test.pkg)})
+		names := []string{}
+		for m, err := range methods {
+			name := receiverTypeName(m.Decl) + "." + m.Decl.Name.Name
+			if p, ok := err.(*errPromoted); ok {
+				name = strings.Join(p.typeNames(), ",") + "." + p.Method.Name() + " in " + filepath.Base(fset.Position(m.Decl.Pos()).Filename)
+				if !strings.HasPrefix(p.Error(), test.promoted) {
+					t.Errorf("%s: %s: got %q, want it to start with %q", test.pkg, name, p.Error(), test.promoted)
+				}
+				err = p.err
+			}
+			if _, ok := err.(*errNotExists); !ok {
+				t.Errorf("%s: %s: unexpected error: %v", test.pkg, name, err)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%s: got %v, want %v", test.pkg, names, test.want)
+		}
+	}
+}
+
+func TestInjectEmbedded(t *testing.T) {
+	savedContextFlag := useContextFlag
+	defer func() {
+		useContextFlag = savedContextFlag
+	}()
+	useContextFlag = false
+	testInject(t, "iface", "embed", 1)
+}
+
+func TestCheckJSON(t *testing.T) {
+	var results []checkResult
+	for _, test := range []string{"test3", "test5"} {
+		ps, interfaces, methods := doCheck(t, []string{path.Join(testPackagePrefix, failingPrefix, test)})
+		results = append(results, checkResults(ps.fset, ps.info, interfaces, methods)...)
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%s: %v", buf.String(), err)
+	}
+	type result struct {
+		pos, method, iface, kind, text string
+	}
+	iface := testPackagePrefix + "/iface."
+	logCall := "\n\tdefer apilog.LogCall(nil)(nil) " + logCallComment
+	want := []result{
+		{"test3.go:11:1", "Type1.Method1", iface + "Interface1", kindNotExists, logCall + "\n"},
+		{"test3.go:12:1", "Type1.Method2", iface + "Interface1", kindNotExists, logCall + "\n"},
+		{"test3.go:24:1", "HalfType2.Method1", iface + "Interface1", kindNotExists, logCall + "\n"},
+		{"test3.go:31:1", "HalfType3.Method2", iface + "Interface1", kindNotExists, logCall + "\n"},
+		{"test5.go:14:1", "Type.ReturnsSomething", iface + "ReturnsValueInterface", kindInvalid, "\n\tdefer apilog.LogCallf(nil, \"a=%v\", a)(nil, \"b=%v\", &b) " + logCallComment},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d: %s", len(got), len(want), buf.String())
+	}
+	for i, w := range want {
+		g := got[i]
+		fix := g["fix"].([]interface{})
+		lastEdit := fix[len(fix)-1].(map[string]interface{})
+		ifaces := []string{}
+		for _, i := range g["interfaces"].([]interface{}) {
+			ifaces = append(ifaces, i.(string))
+		}
+		r := result{
+			pos:    filepath.Base(g["position"].(string)),
+			method: g["method"].(string),
+			iface:  strings.Join(ifaces, ","),
+			kind:   g["kind"].(string),
+			text:   lastEdit["text"].(string),
+		}
+		if r != w {
+			t.Errorf("%d: got %#v, want %#v", i, r, w)
+		}
+		// test3 has no imports, so every fix must add one.
+		if strings.HasPrefix(r.pos, "test3") {
+			if got, want := len(fix), 2; got != want {
+				t.Errorf("%d: got %d edits, want %d", i, got, want)
+			} else if got, want := fix[0].(map[string]interface{})["text"], "import \"v.io/x/ref/lib/apilog\"\n"; got != want {
+				t.Errorf("%d: got %q, want %q", i, got, want)
+			}
+		}
+	}
+}
+
+func TestCheckJSONPromoted(t *testing.T) {
+	ps, interfaces, methods := doCheck(t, []string{path.Join(testPackagePrefix, "embed", "test2")})
+	got := []string{}
+	for _, r := range checkResults(ps.fset, ps.info, interfaces, methods) {
+		got = append(got, filepath.Base(r.filename)+": "+r.Method+" "+strings.Join(r.Interfaces, ","))
+	}
+	iface := testPackagePrefix + "/iface.Interface1"
+	want := []string{
+		"base.go: Type1.Method1 " + iface,
+		"base.go: Type3.Method1 " + iface,
+		"test2.go: Type4.Method1 " + iface,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
 }
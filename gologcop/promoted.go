@@ -0,0 +1,91 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// promotion describes a method that types in an implementation package
+// get by embedding a type declared in another package.
+type promotion struct {
+	// Types are the implementation types, in the order of their names.
+	Types []*types.TypeName
+	// Method is the promoted method, as declared in the other package.
+	Method *types.Func
+}
+
+// typeNames returns the names of the implementation types.
+func (p promotion) typeNames() []string {
+	names := []string{}
+	for _, obj := range p.Types {
+		names = append(names, obj.Name())
+	}
+	return names
+}
+
+// embeddedType returns the name of the type that declares the method,
+// qualified by its package path.
+func (p promotion) embeddedType() string {
+	typ := p.Method.Type().(*types.Signature).Recv().Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		obj := named.Obj()
+		return obj.Pkg().Path() + "." + obj.Name()
+	}
+	return typ.String()
+}
+
+// errPromoted is the error for methods that are promoted from a type
+// declared in another package and lack a valid log construct there.
+type errPromoted struct {
+	err error
+	promotion
+}
+
+func (e errPromoted) Error() string {
+	names := e.typeNames()
+	methods := []string{}
+	for _, name := range names {
+		methods = append(methods, name+"."+e.Method.Name())
+	}
+	verb, wrapper := "is", "a wrapper method on "+names[0]
+	if len(names) > 1 {
+		verb, wrapper = "are", "wrapper methods on each of "+strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s %s promoted from %s: the log call must be added to it in %s, or to %s: %v",
+		strings.Join(methods, ", "), verb, e.embeddedType(), e.Method.Pkg().Path(), wrapper, e.err)
+}
+
+// checkPromotedMethods checks the declarations of the promoted methods in
+// the packages that declare them, returning errors for those that lack a
+// valid log construct.
+func checkPromotedMethods(ps *parseState, redact redactRules, promoted map[token.Pos]promotion) (map[funcDeclRef]error, error) {
+	byPkg := map[string]map[token.Pos]struct{}{}
+	for pos, p := range promoted {
+		path := p.Method.Pkg().Path()
+		if byPkg[path] == nil {
+			byPkg[path] = map[token.Pos]struct{}{}
+		}
+		byPkg[path][pos] = exists
+	}
+	result := map[funcDeclRef]error{}
+	for path, positions := range byPkg {
+		_, files := ps.parsedPackage(path)
+		methods, err := functionDeclarationsAtPositions(ps.fset, files, ps.info, redact, positions)
+		if err != nil {
+			return nil, err
+		}
+		for m, err := range checkMethods(ps.info, redact, methods) {
+			result[m] = &errPromoted{err, promoted[m.Decl.Name.Pos()]}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,246 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// redactedValue is logged in place of sensitive values.
+const redactedValue = "<redacted>"
+
+// redactRule describes values that must not be logged.  A value is
+// sensitive if all of the attributes of a rule match it, or if it is a
+// struct, or a pointer to one, with a sensitive field.
+type redactRule struct {
+	// Name matches the names of parameters, results, variables and fields.
+	Name string `xml:"name,attr,omitempty"`
+	// Type matches types, qualified by their package paths, e.g.
+	// "v\.io/v23/security\.PrivateKey".  Pointers to, and slices and
+	// arrays of, a matching type match too.
+	Type string `xml:"type,attr,omitempty"`
+	// Tag is a struct tag key, optionally followed by a colon and one of
+	// the comma-separated options of its value, e.g. "log:redact".  It
+	// matches fields whose tags have that key and option.
+	Tag string `xml:"tag,attr,omitempty"`
+}
+
+var errEmptyRedact = errors.New("at least one of name, type and tag must be specified")
+
+func (r redactRule) String() string {
+	s := []string{}
+	for _, attr := range []struct{ name, value string }{{"name", r.Name}, {"type", r.Type}, {"tag", r.Tag}} {
+		if len(attr.value) > 0 {
+			s = append(s, fmt.Sprintf("%s=%q", attr.name, attr.value))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func (r redactRule) Validate() error {
+	if len(r.Name) == 0 && len(r.Type) == 0 && len(r.Tag) == 0 {
+		return errEmptyRedact
+	}
+	for _, expr := range []string{r.Name, r.Type} {
+		if _, err := wholeRegexp(expr); err != nil {
+			return err
+		}
+	}
+	if strings.HasPrefix(r.Tag, ":") {
+		return fmt.Errorf("tag %q has no key", r.Tag)
+	}
+	return nil
+}
+
+// Matches returns true if the rule matches a value with the given name,
+// type and, for a struct field, tag.
+func (r redactRule) Matches(name string, typ types.Type, tag string) bool {
+	if len(r.Name) > 0 && !matchesWhole(r.Name, name) {
+		return false
+	}
+	if len(r.Type) > 0 && !matchesWhole(r.Type, types.TypeString(elemType(typ), nil)) {
+		return false
+	}
+	if len(r.Tag) > 0 && !tagMatches(r.Tag, tag) {
+		return false
+	}
+	return true
+}
+
+// elemType strips pointers, slices and arrays from typ.
+func elemType(typ types.Type) types.Type {
+	for {
+		switch t := typ.(type) {
+		case *types.Pointer:
+			typ = t.Elem()
+		case *types.Slice:
+			typ = t.Elem()
+		case *types.Array:
+			typ = t.Elem()
+		default:
+			return typ
+		}
+	}
+}
+
+// tagMatches returns true if tag has the key, and option if any, in spec.
+func tagMatches(spec, tag string) bool {
+	key, option := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		key, option = spec[:i], spec[i+1:]
+	}
+	value, ok := reflect.StructTag(tag).Lookup(key)
+	if !ok {
+		return false
+	}
+	if len(option) == 0 {
+		return true
+	}
+	for _, o := range strings.Split(value, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// redactRules holds the redaction rules that apply to a package.
+type redactRules []redactRule
+
+// sensitive returns the rule that makes a value with the given name, type
+// and tag sensitive, or nil if it isn't.
+func (rs redactRules) sensitive(name string, typ types.Type, tag string) *redactRule {
+	if len(rs) == 0 {
+		return nil
+	}
+	for i, r := range rs {
+		if r.Matches(name, typ, tag) {
+			return &rs[i]
+		}
+	}
+	return rs.sensitiveField(typ, map[types.Type]bool{})
+}
+
+// sensitiveField returns the rule that makes a field of typ, which may be
+// a pointer to a struct, sensitive, or nil if there is none.
+func (rs redactRules) sensitiveField(typ types.Type, seen map[types.Type]bool) *redactRule {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		for j, r := range rs {
+			if r.Matches(field.Name(), field.Type(), st.Tag(i)) {
+				return &rs[j]
+			}
+		}
+		if r := rs.sensitiveField(field.Type(), seen); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// leak returns an errLeak if the leading n statements of decl, which make
+// up its log construct, refer to a sensitive value.
+func (rs redactRules) leak(info *types.Info, decl *ast.FuncDecl, n int) error {
+	if len(rs) == 0 || decl.Body == nil {
+		return nil
+	}
+	stmts := decl.Body.List
+	if n > len(stmts) {
+		n = len(stmts)
+	}
+	var err error
+	for _, stmt := range stmts[:n] {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			switch node := node.(type) {
+			case *ast.SelectorExpr:
+				sel, ok := info.Selections[node]
+				if !ok || sel.Kind() != types.FieldVal {
+					return true
+				}
+				field := sel.Obj().(*types.Var)
+				if r := rs.sensitive(field.Name(), field.Type(), fieldTag(sel)); r != nil {
+					err = &errLeak{fmt.Sprintf("logs sensitive field %s, redacted by %v", types.ExprString(node), r)}
+				}
+				// Only the selected field is logged, not the value it
+				// is selected from.
+				return err == nil && !isFieldPath(node.X)
+			case *ast.Ident:
+				v, ok := info.Uses[node].(*types.Var)
+				if !ok || v.IsField() {
+					return true
+				}
+				if r := rs.sensitive(v.Name(), v.Type(), ""); r != nil {
+					err = &errLeak{fmt.Sprintf("logs sensitive value %s, redacted by %v", v.Name(), r)}
+				}
+			}
+			return err == nil
+		})
+	}
+	return err
+}
+
+// isFieldPath returns true if expr is a variable, or a field selected from
+// one.
+func isFieldPath(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isFieldPath(expr.X)
+	case *ast.ParenExpr:
+		return isFieldPath(expr.X)
+	case *ast.StarExpr:
+		return isFieldPath(expr.X)
+	}
+	return false
+}
+
+// fieldTag returns the struct tag of the field selected by sel.
+func fieldTag(sel *types.Selection) string {
+	typ := sel.Recv()
+	for i, index := range sel.Index() {
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return ""
+		}
+		if i == len(sel.Index())-1 {
+			return st.Tag(index)
+		}
+		typ = st.Field(index).Type()
+	}
+	return ""
+}
+
+// errLeak is the error for methods whose log construct logs a sensitive
+// value.
+type errLeak struct {
+	message string
+}
+
+func (e errLeak) Error() string {
+	return e.message
+}
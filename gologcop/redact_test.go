@@ -0,0 +1,143 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const redactSrc = `package p
+
+type PrivateKey []byte
+
+type Creds struct {
+	User  string
+	Token string ` + "`log:\"redact,omitempty\"`" + `
+}
+
+type Login struct {
+	Creds
+	Attempts int
+}
+
+type T struct{}
+
+func logf(format string, args ...interface{}) func(string, ...interface{}) { return nil }
+
+func (T) Password(user, password string, n int) {
+	defer logf("user=%v,password=%v", user, password)("")
+}
+
+func (T) Sign(key PrivateKey, keys []PrivateKey) (sig string, err error) {
+	defer logf("")("sig=%v", &sig)
+	return
+}
+
+func (T) Auth(c *Creds, l Login) {
+	defer logf("c=%v", c.User)("")
+}
+
+func (T) Token(l *Login) {
+	defer logf("l=%v", l.Token)("")
+}
+
+func (T) Other(l Login) {
+	defer logf("l=%v", l.Attempts)("")
+}
+`
+
+var testRedactRules = redactRules{
+	{Name: "password"},
+	{Type: `p\.PrivateKey`},
+	{Tag: "log:redact"},
+}
+
+func parseRedactSrc(t *testing.T) (*types.Info, map[string]*ast.FuncDecl) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", redactSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	if _, err := new(types.Config).Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	decls := map[string]*ast.FuncDecl{}
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Recv != nil {
+			decls[decl.Name.Name] = decl
+		}
+	}
+	return info, decls
+}
+
+func TestRedactCall(t *testing.T) {
+	info, decls := parseRedactSrc(t)
+	if err := initInjectorFlags(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method, call string
+	}{
+		{"Password", `apilog.LogCallf(nil, "user=%.10s...,password=<redacted>,n=%v", user,n)(nil, "")`},
+		{"Sign", `apilog.LogCallf(nil, "key=<redacted>,keys=<redacted>")(nil, "sig=%.10s...,err=%v", &sig,&err)`},
+		{"Auth", `apilog.LogCallf(nil, "c=<redacted>,l=<redacted>")(nil, "")`},
+		{"Other", `apilog.LogCallf(nil, "l=<redacted>")(nil, "")`},
+	}
+	for _, test := range tests {
+		decl := decls[test.method]
+		call, err := genCall(info, testRedactRules, decl.Type.Params, decl.Type.Results)
+		if err != nil {
+			t.Errorf("%s: %v", test.method, err)
+			continue
+		}
+		if got, want := strings.TrimSpace(strings.TrimSuffix(call, logCallComment)), "defer "+test.call; got != want {
+			t.Errorf("%s: got %s, want %s", test.method, got, want)
+		}
+	}
+	// Without rules, nothing is redacted.
+	decl := decls["Password"]
+	call, err := genCall(info, nil, decl.Type.Params, decl.Type.Results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(call, redactedValue) {
+		t.Errorf("got %s, want no redactions", call)
+	}
+}
+
+func TestRedactLeak(t *testing.T) {
+	info, decls := parseRedactSrc(t)
+	tests := []struct {
+		method, leak string
+	}{
+		{"Password", `logs sensitive value password, redacted by name="password"`},
+		{"Sign", ""},
+		{"Auth", ""},
+		{"Token", `logs sensitive field l.Token, redacted by tag="log:redact"`},
+		{"Other", ""},
+	}
+	for _, test := range tests {
+		err := testRedactRules.leak(info, decls[test.method], 1)
+		switch {
+		case len(test.leak) == 0 && err != nil:
+			t.Errorf("%s: unexpected leak: %v", test.method, err)
+		case len(test.leak) > 0:
+			if _, ok := err.(*errLeak); !ok || err.Error() != test.leak {
+				t.Errorf("%s: got %v, want %s", test.method, err, test.leak)
+			}
+		}
+	}
+}
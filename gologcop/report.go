@@ -0,0 +1,194 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+)
+
+const (
+	// kindNotExists is the kind of the results for methods without a log
+	// construct, i.e. errNotExists.
+	kindNotExists = "not-exists"
+	// kindInvalid is the kind of the results for methods with an invalid
+	// log construct, i.e. errInvalid.
+	kindInvalid = "invalid"
+	// kindLeak is the kind of the results for methods whose log construct
+	// logs a sensitive value, i.e. errLeak.
+	kindLeak = "leak"
+)
+
+// checkResult describes a method that lacks a valid log construct, as
+// printed by check -json.
+type checkResult struct {
+	// Position is the file:line:col of the method declaration.
+	Position string `json:"position"`
+	// Method is the method name, qualified by its receiver type.
+	Method string `json:"method"`
+	// Interfaces are the interfaces that the method is part of, qualified
+	// by their package paths.
+	Interfaces []string `json:"interfaces"`
+	Kind       string   `json:"kind"`
+	Message    string   `json:"message"`
+	// Fix holds the insertions that inject would make for the method.
+	// Each fix is self-contained, so the insertion of the import is repeated
+	// for every method in a file that needs it.  Leaks, and methods
+	// promoted from other packages, have to be fixed by hand, so they have
+	// no insertions.
+	Fix []edit `json:"fix"`
+
+	filename string
+	offset   int
+}
+
+// edit is the insertion of text at a position in a file.
+type edit struct {
+	// Position is the file:line:col of the insertion.
+	Position string `json:"position"`
+	// Offset is the byte offset of the insertion in the file.
+	Offset int    `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// checkResults returns the results for methods, sorted by their positions.
+// Methods promoted to several implementation types have a result for each.
+func checkResults(fset *token.FileSet, info *types.Info, interfaces []*types.Named, methods map[funcDeclRef]error) []checkResult {
+	results := []checkResult{}
+	for m, err := range methods {
+		pos := fset.Position(m.Decl.Pos())
+		kind := kindNotExists
+		method := m.Decl.Name.Name
+		if recv := receiverTypeName(m.Decl); len(recv) > 0 {
+			method = recv + "." + method
+		}
+		cause := err
+		p, promoted := err.(*errPromoted)
+		if promoted {
+			cause = p.err
+		}
+		switch cause.(type) {
+		case *errInvalid:
+			kind = kindInvalid
+		case *errLeak:
+			kind = kindLeak
+		}
+		result := checkResult{
+			Position:   pos.String(),
+			Method:     method,
+			Interfaces: implementedInterfaces(info, m.Decl, interfaces),
+			Kind:       kind,
+			Message:    err.Error(),
+			Fix:        []edit{},
+			filename:   pos.Filename,
+			offset:     pos.Offset,
+		}
+		if promoted {
+			// The method is reported as part of each implementation
+			// type, at the position of its declaration in the other
+			// package.
+			for _, obj := range p.Types {
+				result.Method = obj.Name() + "." + p.Method.Name()
+				result.Interfaces = implementedInterfacesOf([]*types.TypeName{obj}, p.Method.Name(), interfaces)
+				results = append(results, result)
+			}
+			continue
+		}
+		if kind != kindLeak {
+			result.Fix = append(result.Fix, newEdit(fset, m.File, injection(fset, m)))
+			if imp, hasChanges := ensureImportLogPackage(fset, m.File); hasChanges {
+				result.Fix = append([]edit{newEdit(fset, m.File, imp)}, result.Fix...)
+			}
+		}
+		results = append(results, result)
+	}
+	sort.Sort(checkResultSorter(results))
+	return results
+}
+
+func newEdit(fset *token.FileSet, file *ast.File, p patch) edit {
+	pos := fset.Position(fset.File(file.Pos()).Pos(p.Offset))
+	return edit{Position: pos.String(), Offset: p.Offset, Text: p.Text}
+}
+
+// implementedInterfaces returns the names of the interfaces that declare
+// decl and are implemented by its receiver type, or by the types that it is
+// promoted to within its package.
+func implementedInterfaces(info *types.Info, decl *ast.FuncDecl, interfaces []*types.Named) []string {
+	fn, ok := info.Defs[decl.Name].(*types.Func)
+	if !ok || fn.Type().(*types.Signature).Recv() == nil {
+		return []string{}
+	}
+	return implementedInterfacesOf(methodTypes(fn), decl.Name.Name, interfaces)
+}
+
+// methodTypes returns the types declared in the package of the method fn
+// whose method sets include it: its receiver type, and the types that embed
+// the receiver type.
+func methodTypes(fn *types.Func) []*types.TypeName {
+	objs := []*types.TypeName{}
+	scope := fn.Pkg().Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() || types.IsInterface(obj.Type()) {
+			continue
+		}
+		if sel := types.NewMethodSet(types.NewPointer(obj.Type())).Lookup(fn.Pkg(), fn.Name()); sel != nil && sel.Obj() == fn {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// implementedInterfacesOf returns the names of the interfaces that declare
+// the named method and are implemented by any of objs.
+func implementedInterfacesOf(objs []*types.TypeName, method string, interfaces []*types.Named) []string {
+	names := []string{}
+	for _, named := range interfaces {
+		for _, obj := range objs {
+			if _, ok := methodSetVisibleThroughInterfaces(obj.Type(), []*types.Named{named})[method]; ok {
+				obj := named.Obj()
+				names = append(names, obj.Pkg().Path()+"."+obj.Name())
+				break
+			}
+		}
+	}
+	return names
+}
+
+type checkResultSorter []checkResult
+
+func (r checkResultSorter) Len() int {
+	return len(r)
+}
+
+func (r checkResultSorter) Less(i, j int) bool {
+	if r[i].filename != r[j].filename {
+		return r[i].filename < r[j].filename
+	}
+	if r[i].offset != r[j].offset {
+		return r[i].offset < r[j].offset
+	}
+	return r[i].Method < r[j].Method
+}
+
+func (r checkResultSorter) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("MarshalIndent(%v) failed: %v", v, err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
@@ -0,0 +1,244 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// logTemplate describes a log construct by way of a file of text/template
+// definitions.  The "inject" template renders the statements to inject at the
+// beginning of a method.  The optional "match" template renders a regular
+// expression that recognises an existing construct in the leading statements
+// of a method, printed one per line; if it is not defined, an existing
+// construct must be identical to the one that would be injected.  The
+// optional "import" template renders the import declaration required by the
+// construct, in the form accepted by the -import flag.  All templates but
+// "import" are executed with a templateData value.
+type logTemplate struct {
+	path     string
+	tmpl     *template.Template
+	matchers map[string]*regexp.Regexp
+}
+
+// templateData is the data that the "inject" and "match" templates are
+// executed with.
+type templateData struct {
+	// Pkg is the package name to use at call sites.
+	Pkg string
+	// Receiver is the name of the receiver type of the method.
+	Receiver string
+	// Method is the name of the method.
+	Method string
+	// Context is the name of the context parameter, or empty if there is
+	// none.
+	Context string
+	// Params are the parameters of the method, excluding the context.
+	Params logArgs
+	// Results are the results of the method, logged via their addresses.
+	Results logArgs
+}
+
+var templateFuncs = template.FuncMap{
+	"regexp": regexp.QuoteMeta,
+}
+
+// initTemplate loads the template in the given file, and sets the import to
+// be injected from it.
+func initTemplate(path string) error {
+	t, err := loadTemplate(path)
+	if err != nil {
+		return err
+	}
+	spec, err := t.render("import", nil)
+	if err != nil {
+		return err
+	}
+	if spec = strings.TrimSpace(spec); len(spec) == 0 {
+		// The construct needs no import.
+		injectImportTag, injectImportPath, injectPackage = "", "", ""
+	} else if err := setInjectImport(spec); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	logTmpl, injectCall = t, ""
+	return nil
+}
+
+func loadTemplate(path string) (*logTemplate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Lookup("inject") == nil {
+		return nil, fmt.Errorf("%s: no %q template defined", path, "inject")
+	}
+	return &logTemplate{
+		path:     path,
+		tmpl:     tmpl,
+		matchers: make(map[string]*regexp.Regexp),
+	}, nil
+}
+
+// render executes the named template, returning the empty string if it is
+// not defined.
+func (t *logTemplate) render(name string, data interface{}) (string, error) {
+	if t.tmpl.Lookup(name) == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// statement returns the log construct to be injected into decl.
+func (t *logTemplate) statement(info *types.Info, redact redactRules, decl *ast.FuncDecl) (logStatement, error) {
+	params, context := contextParam(info, decl.Type.Params, true)
+	if context == "nil" {
+		context = ""
+	}
+	paramArgs, err := genFmt(info, redact, params, false)
+	if err != nil {
+		return logStatement{}, err
+	}
+	resultArgs, err := genFmt(info, redact, decl.Type.Results, true)
+	if err != nil {
+		return logStatement{}, err
+	}
+	data := templateData{
+		Pkg:      injectPackage,
+		Receiver: receiverTypeName(decl),
+		Method:   decl.Name.Name,
+		Context:  context,
+		Params:   paramArgs,
+		Results:  resultArgs,
+	}
+	src, err := t.render("inject", data)
+	if err != nil {
+		return logStatement{}, err
+	}
+	stmts, err := parseStmts(src)
+	if err != nil {
+		return logStatement{}, fmt.Errorf("%s: invalid statements %q: %v", t.path, src, err)
+	}
+	if len(stmts) == 0 {
+		return logStatement{}, fmt.Errorf("%s: no statements to inject", t.path)
+	}
+	match, err := t.render("match", data)
+	if err != nil {
+		return logStatement{}, err
+	}
+	if t.tmpl.Lookup("match") == nil {
+		match = "^" + regexp.QuoteMeta(printStmts(stmts)) + "$"
+	}
+	if _, err := t.matcher(match); err != nil {
+		return logStatement{}, fmt.Errorf("%s: %v", t.path, err)
+	}
+	lines := strings.Split(strings.TrimSpace(src), "\n")
+	lines[0] += " " + logCallComment
+	return logStatement{
+		Text:     "\n\t" + strings.Join(lines, "\n\t"),
+		NumStmts: len(stmts),
+		Match:    match,
+	}, nil
+}
+
+func (t *logTemplate) matcher(expr string) (*regexp.Regexp, error) {
+	if re, ok := t.matchers[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	t.matchers[expr] = re
+	return re, nil
+}
+
+// validate returns an error if method does not begin with a construct
+// matching the template.
+func (t *logTemplate) validate(method funcDeclRef) error {
+	stmts := method.Decl.Body.List
+	if len(stmts) == 0 {
+		return &errNotExists{"empty method"}
+	}
+	re, err := t.matcher(method.LogCall.Match)
+	if err != nil {
+		return &errInvalid{err.Error()}
+	}
+	n := method.LogCall.NumStmts
+	if n > len(stmts) {
+		n = len(stmts)
+	}
+	if re.MatchString(printStmts(stmts[:n])) {
+		return nil
+	}
+	// A leading statement that refers to the injected package is most
+	// likely a construct that has since been edited by hand.
+	if first := printStmts(stmts[:1]); len(injectPackage) > 0 && strings.Contains(first, injectPackage+".") {
+		return &errInvalid{fmt.Sprintf("%q does not match the template %s", first, t.path)}
+	}
+	return &errNotExists{"no statement matching the template " + t.path}
+}
+
+// receiverTypeName returns the name of the receiver type of decl, without
+// any pointer indirection.
+func receiverTypeName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	typ := decl.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	// Generic receivers are named without their type parameters.
+	switch index := typ.(type) {
+	case *ast.IndexExpr:
+		typ = index.X
+	case *ast.IndexListExpr:
+		typ = index.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// parseStmts parses src as a list of statements.
+func parseStmts(src string) ([]ast.Stmt, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p; func _() {\n"+src+"\n}", 0)
+	if err != nil {
+		return nil, err
+	}
+	return file.Decls[0].(*ast.FuncDecl).Body.List, nil
+}
+
+// printStmts prints stmts one per line, discarding their original layout so
+// that equivalent statements always print the same way.
+func printStmts(stmts []ast.Stmt) string {
+	lines := []string{}
+	for _, stmt := range stmts {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, token.NewFileSet(), stmt)
+		lines = append(lines, buf.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test1 has no log statements, and relies on .gologcop files to exclude
+// some of its methods from the checks.
+package test1
+
+// Type1.Method2 is excluded by the package section of the parent config.
+type Type1 struct{}
+
+func (Type1) Method1()    {}
+func (Type1) Method2(int) {}
+
+// Stream is excluded by the parent config, as is the Method1 that it gets
+// from streamBase, which implements no interface by itself.
+type Stream struct {
+	streamBase
+}
+
+func (Stream) Method2(int) {}
+
+type streamBase struct{}
+
+func (streamBase) Method1() {}
+
+// Type2 is excluded by the parent config, but included again by the config
+// in this directory.
+type Type2 struct{}
+
+func (Type2) ReturnsSomething(a int) int { return a }
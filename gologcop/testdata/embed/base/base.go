@@ -0,0 +1,15 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// base declares a type that is embedded by the implementations in the
+// embed test packages.
+package base
+
+type Base struct{}
+
+func (Base) Method1() {}
+
+func (*Base) Method2(a int) {
+	//nologcall
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test1 implements interfaces via embedding and with generic types.
+package test1
+
+import "v.io/x/devtools/gologcop/testdata/embed/base"
+
+// Type1 gets its methods from base.Base, which is in another package.
+type Type1 struct {
+	base.Base
+}
+
+type inner struct{}
+
+func (inner) Method1() {}
+
+// Type2 gets Method1 from inner, and declares Method2 on its pointer.
+type Type2 struct {
+	inner
+}
+
+func (*Type2) Method2(a int) {}
+
+// Generic implements the interfaces whatever it is instantiated with.
+type Generic[T any] struct {
+	v T
+}
+
+func (Generic[T]) Method1() {}
+
+func (*Generic[T]) Method2(a int) {}
+
+func (g Generic[T]) ReturnsSomething(a int) T {
+	return g.v
+}
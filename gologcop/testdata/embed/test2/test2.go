@@ -0,0 +1,31 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test2 has several types that get their methods from base.Base, and a
+// .gologcop file that excludes some of them.
+package test2
+
+import "v.io/x/devtools/gologcop/testdata/embed/base"
+
+// Type1 and Type3 both get Method1 from base.Base, so a wrapper method is
+// needed on each of them.
+type Type1 struct {
+	base.Base
+}
+
+type Type3 struct {
+	base.Base
+}
+
+// Excluded is excluded by type.
+type Excluded struct {
+	base.Base
+}
+
+// Type4 declares its own methods, of which Method2 is excluded by name.
+type Type4 struct{}
+
+func (Type4) Method1() {}
+
+func (*Type4) Method2(a int) {}
@@ -0,0 +1,19 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test1 tests that removal deletes the imports that are no longer used.
+package test1
+
+import (
+	"fmt"
+
+	"v.io/x/ref/lib/apilog"
+)
+
+type Type1 struct{}
+
+func (Type1) Method1() {
+	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+	fmt.Println()
+}
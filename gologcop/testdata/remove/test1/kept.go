@@ -0,0 +1,16 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test1
+
+import "v.io/x/ref/lib/apilog"
+
+type Type2 struct{}
+
+func (Type2) Method1() {
+	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+}
+
+// keep still uses apilog, so its import must not be removed.
+var keep = apilog.LogCall
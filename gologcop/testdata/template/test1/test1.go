@@ -0,0 +1,19 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test1 has no log statements, to test injection from a template.
+package test1
+
+type Type1 struct{}
+
+func (Type1) Method1() {
+}
+
+func (Type1) Method2(a int) {
+	_ = a
+}
+
+func (Type1) ReturnsSomething(a int) (b int) {
+	return a
+}
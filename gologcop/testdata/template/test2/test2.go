@@ -0,0 +1,36 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test2 has log statements matching the slog template, to test checking
+// and removal with a template.
+package test2
+
+import "log/slog"
+
+type Type1 struct{}
+
+func (Type1) Method1() {
+	slog.Info("Type1.Method1") // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+}
+
+func (Type1) Method2(a int) {
+	slog.Info("Type1.Method2", "a", a, "extra", true)
+	_ = a
+}
+
+func (Type1) ReturnsSomething(a int) (b int) {
+	//nologcall
+	return a
+}
+
+type Type2 struct{}
+
+func (Type2) Method1() {
+	slog.Info("Method1")
+}
+
+func (Type2) Method2(a int) {
+	// An unrelated statement.
+	_ = a
+}
@@ -0,0 +1,16 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test1 has a type error in a function body, which is only reported.
+package test1
+
+import "v.io/x/ref/lib/apilog"
+
+type Type1 struct{}
+
+func (Type1) Method1() {
+	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+	var n int = "not an int"
+	_ = n
+}
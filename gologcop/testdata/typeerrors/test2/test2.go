@@ -0,0 +1,16 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// test2 has a type error in a declaration, which fails the type check.
+package test2
+
+import "v.io/x/ref/lib/apilog"
+
+var n int = "not an int"
+
+type Type1 struct{}
+
+func (Type1) Method1() {
+	defer apilog.LogCall()() // gologcop: DO NOT EDIT, MUST BE FIRST STATEMENT
+}
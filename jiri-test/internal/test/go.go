@@ -1407,13 +1407,19 @@ func vanadiumGoDepcop(jirix *jiri.X, testName string, _ ...Opt) (_ *test.Result,
 		return nil, newInternalError(err, "godepcop-build")
 	}
 
-	// Run the godepcop tool.
-	var out bytes.Buffer
-	if err := s.Capture(&out, &out).Last("jiri", "run", binary, "check", "v.io/..."); err != nil {
-		if err := xunit.CreateFailureReport(jirix, testName, "RunGoDepcop", "CheckDependencies", "dependencies check failure", out.String()); err != nil {
+	// Run the godepcop tool, which reports violations per package in xUnit
+	// format.
+	var out, errOut bytes.Buffer
+	if err := s.Capture(&out, &errOut).Last("jiri", "run", binary, "check", "-format=xunit", "v.io/..."); err != nil {
+		if out.Len() == 0 {
+			// The check didn't run to completion; report the error output.
+			if err := xunit.CreateFailureReport(jirix, testName, "RunGoDepcop", "CheckDependencies", "dependencies check failure", errOut.String()); err != nil {
+				return nil, err
+			}
+		} else if err := jirix.NewSeq().WriteFile(xunit.ReportPath(testName), out.Bytes(), os.FileMode(0644)).Done(); err != nil {
 			return nil, err
 		}
-		fmt.Fprintf(jirix.Stderr(), "%v", out.String())
+		fmt.Fprintf(jirix.Stderr(), "%v", errOut.String())
 		return &test.Result{Status: test.Failed}, nil
 	}
 	return &test.Result{Status: test.Passed}, nil
@@ -0,0 +1,214 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// defaultBackend is the backend used when none is configured.
+const defaultBackend = "vtrace"
+
+// backend describes a tracing package that spans are created with, and the
+// context that functions must take as their first argument to get one.
+type backend struct {
+	// pkgPath is the import path of the tracing package.
+	pkgPath string
+	// ctxPath is the import path of the package that declares the context
+	// type, and ctxType formats the type given the name that package is
+	// imported as.
+	ctxPath, ctxType string
+	// start is the function in the tracing package that spans are begun
+	// with, annotate is the method that records arguments on them, and
+	// finish is the method that ends them.
+	start, annotate, finish string
+	// attrPath and codesPath are the import paths of the packages that
+	// recording arguments and errors needs, if any.
+	attrPath, codesPath string
+	// skip holds the import paths of the packages, besides those above, that
+	// the tracing package depends on and so must not be annotated.
+	skip []string
+	// tpl is the template for the statements that begin and end a span.
+	tpl *template.Template
+}
+
+// spanComment is appended to the first statement of the spans that inject
+// adds, which are the only spans that remove removes.
+const spanComment = "// tracify: DO NOT EDIT, MUST BE FIRST STATEMENTS"
+
+var vtraceTpl = template.Must(template.New("vtrace").Parse(`
+	{{.CtxName}}, vspan := {{.TraceName}}.WithNewSpan({{.CtxName}}, {{printf "%q" .SpanName}}) ` + spanComment + `
+{{- if .Args}}
+	vspan.Annotatef({{printf "%q" .ArgsFormat}}{{range .Args}}, {{.Name}}{{end}})
+{{- end}}
+{{- if .Err}}
+	defer func() {
+		if {{.Err}} != nil {
+			vspan.Annotatef("error: %v", {{.Err}})
+		}
+		vspan.Finish()
+	}()
+{{- else}}
+	defer vspan.Finish()
+{{- end}}
+`))
+
+var otelTpl = template.Must(template.New("otel").Parse(`
+	{{.CtxName}}, span := {{.TraceName}}.Tracer({{printf "%q" .Tracer}}).Start({{.CtxName}}, {{printf "%q" .SpanName}}) ` + spanComment + `
+{{- if .Args}}
+	span.SetAttributes({{range $i, $arg := .Args}}{{if $i}}, {{end}}{{$.AttrPkg}}.{{$arg.Attr}}({{printf "%q" $arg.Name}}, {{$arg.Value}}){{end}})
+{{- end}}
+{{- if .Err}}
+	defer func() {
+		if {{.Err}} != nil {
+			span.RecordError({{.Err}})
+			span.SetStatus({{.CodesPkg}}.Error, {{.Err}}.Error())
+		}
+		span.End()
+	}()
+{{- else}}
+	defer span.End()
+{{- end}}
+`))
+
+// backends maps the names that can be given to -backend, or the backend
+// attribute of the config file, to the backends.
+var backends = map[string]*backend{
+	"vtrace": {
+		pkgPath:  "v.io/v23/vtrace",
+		ctxPath:  "v.io/v23/context",
+		ctxType:  "*%s.T",
+		start:    "WithNewSpan",
+		annotate: "Annotatef",
+		finish:   "Finish",
+		skip:     []string{"v.io/v23/verror", "v.io/x/ref/runtime/internal/vtrace"},
+		tpl:      vtraceTpl,
+	},
+	"otel": {
+		pkgPath:   "go.opentelemetry.io/otel",
+		ctxPath:   "context",
+		ctxType:   "%s.Context",
+		start:     "Tracer",
+		annotate:  "SetAttributes",
+		finish:    "End",
+		attrPath:  "go.opentelemetry.io/otel/attribute",
+		codesPath: "go.opentelemetry.io/otel/codes",
+		tpl:       otelTpl,
+	},
+}
+
+// lookupBackend returns the backend called name.
+func lookupBackend(name string) (*backend, error) {
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q, must be vtrace or otel", name)
+	}
+	return b, nil
+}
+
+// imports returns the import paths of the packages that the spans of b
+// may use.
+func (b *backend) imports() []string {
+	paths := []string{b.pkgPath}
+	for _, p := range []string{b.attrPath, b.codesPath} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// skips returns true if pkg must not be annotated when annotating the
+// dependencies of packages: the standard library, and the packages of b and
+// the packages below them, which annotating would make import themselves.
+func (b *backend) skips(pkg *build.Package) bool {
+	if pkg.Goroot {
+		return true
+	}
+	for _, p := range append(b.imports(), b.skip...) {
+		if pkg.ImportPath == p || strings.HasPrefix(pkg.ImportPath, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// importOf returns the import of the package at pkgPath in f and the name it
+// is imported as, or nil and the empty string if there is none.  Blank and dot
+// imports are ignored, since the package can't be referred to by their names.
+func importOf(f *ast.File, pkgPath string) (*ast.ImportSpec, string) {
+	quoted := strconv.Quote(pkgPath)
+	for _, i := range f.Imports {
+		if i.Path.Value != quoted {
+			continue
+		}
+		if i.Name == nil {
+			return i, path.Base(pkgPath)
+		}
+		if i.Name.Name != "_" && i.Name.Name != "." {
+			return i, i.Name.Name
+		}
+	}
+	return nil, ""
+}
+
+// contextType returns the context type of b as it is written in f, or the
+// empty string if f doesn't import the package that declares it.  The
+// context package is identified by its import path rather than its name,
+// since both v.io/v23/context and the standard library's context are
+// called context.
+func (b *backend) contextType(f *ast.File) string {
+	_, name := importOf(f, b.ctxPath)
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(b.ctxType, name)
+}
+
+// isStart returns true if expr begins a span, i.e. it is a chain of calls and
+// selections that starts with the start function of b, imported as name, e.g.
+// otel.Tracer("p").Start.
+func (b *backend) isStart(expr ast.Expr, name string) bool {
+	for {
+		switch e := expr.(type) {
+		case *ast.CallExpr:
+			expr = e.Fun
+		case *ast.SelectorExpr:
+			if isSelector(e, name, b.start) {
+				return true
+			}
+			expr = e.X
+		default:
+			return false
+		}
+	}
+}
+
+// isFinish returns true if call ends the span, either directly or at the end
+// of a function literal that first records an error on it.
+func (b *backend) isFinish(call *ast.CallExpr, span string) bool {
+	if len(call.Args) != 0 {
+		return false
+	}
+	if isSelector(call.Fun, span, b.finish) {
+		return true
+	}
+	lit, ok := call.Fun.(*ast.FuncLit)
+	if !ok || len(lit.Body.List) == 0 {
+		return false
+	}
+	last, ok := lit.Body.List[len(lit.Body.List)-1].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	finish, ok := last.X.(*ast.CallExpr)
+	return ok && len(finish.Args) == 0 && isSelector(finish.Fun, span, b.finish)
+}
@@ -0,0 +1,217 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"regexp"
+	"text/template"
+)
+
+// defaultSpanName is the span-name template used when none is configured,
+// which names spans after the bare function name.
+const defaultSpanName = "{{.Func}}"
+
+// config selects the functions to instrument, and describes the names of
+// their spans, the backend they are created with and what is recorded on
+// them.  A function is instrumented if there are no include rules or it
+// matches one of them, and it matches no exclude rule.
+type config struct {
+	XMLName  struct{} `xml:"tracify"`
+	SpanName string   `xml:"span-name,attr,omitempty"`
+	Backend  string   `xml:"backend,attr,omitempty"`
+	// Record is a regular expression for the names of the scalar arguments
+	// to record on spans.  If it is set, returned errors are recorded too.
+	Record  string `xml:"record,attr,omitempty"`
+	Include []rule `xml:"include"`
+	Exclude []rule `xml:"exclude"`
+
+	spanTpl *template.Template
+	backend *backend
+	record  *regexp.Regexp
+}
+
+// rule matches functions by regular expressions on their package import
+// path, name and receiver type name, which is empty for functions that are
+// not methods.  A rule matches a function if all of its expressions do.
+type rule struct {
+	Pkg  string `xml:"pkg,attr,omitempty"`
+	Func string `xml:"func,attr,omitempty"`
+	Recv string `xml:"recv,attr,omitempty"`
+
+	pkg, fn, recv *regexp.Regexp
+}
+
+// spanData is the data that span-name templates are executed with.
+type spanData struct {
+	// Pkg is the package name.
+	Pkg string
+	// PkgPath is the package import path.
+	PkgPath string
+	// Recv is the receiver type name, without any pointer indirection or
+	// type parameters, or empty if the function is not a method.
+	Recv string
+	// Func is the function name.
+	Func string
+}
+
+// loadConfig reads the config file at path, or returns the default config
+// if path is empty.  A non-empty spanName, backend or record overrides the
+// corresponding attribute in the file.
+func loadConfig(path, spanName, backend, record string) (*config, error) {
+	c := &config{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := xml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if spanName != "" {
+		c.SpanName = spanName
+	}
+	if c.SpanName == "" {
+		c.SpanName = defaultSpanName
+	}
+	if backend != "" {
+		c.Backend = backend
+	}
+	if c.Backend == "" {
+		c.Backend = defaultBackend
+	}
+	if record != "" {
+		c.Record = record
+	}
+	if err := c.init(); err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// init looks up the backend, and compiles the span-name template and the
+// regular expressions of the record attribute and the rules.
+func (c *config) init() error {
+	b, err := lookupBackend(c.Backend)
+	if err != nil {
+		return err
+	}
+	c.backend = b
+	tpl, err := template.New("span-name").Parse(c.SpanName)
+	if err != nil {
+		return err
+	}
+	c.spanTpl = tpl
+	if c.Record != "" {
+		if c.record, err = regexp.Compile(c.Record); err != nil {
+			return err
+		}
+	}
+	for _, rules := range [][]rule{c.Include, c.Exclude} {
+		for i := range rules {
+			if err := rules[i].compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *rule) compile() error {
+	if r.Pkg == "" && r.Func == "" && r.Recv == "" {
+		return fmt.Errorf("at least one of pkg, func and recv must be specified")
+	}
+	for _, re := range []struct {
+		expr string
+		dst  **regexp.Regexp
+	}{{r.Pkg, &r.pkg}, {r.Func, &r.fn}, {r.Recv, &r.recv}} {
+		if re.expr == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(re.expr)
+		if err != nil {
+			return err
+		}
+		*re.dst = compiled
+	}
+	return nil
+}
+
+func (r *rule) matches(d spanData) bool {
+	return (r.pkg == nil || r.pkg.MatchString(d.PkgPath)) &&
+		(r.fn == nil || r.fn.MatchString(d.Func)) &&
+		(r.recv == nil || r.recv.MatchString(d.Recv))
+}
+
+// selects returns true if the function described by d is to be
+// instrumented.
+func (c *config) selects(d spanData) bool {
+	included := len(c.Include) == 0
+	for i := range c.Include {
+		if c.Include[i].matches(d) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for i := range c.Exclude {
+		if c.Exclude[i].matches(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// recording returns true if arguments and errors are recorded on spans.
+func (c *config) recording() bool {
+	return c.record != nil
+}
+
+// recordArg returns true if the argument called name is recorded on spans,
+// provided it has a scalar type.
+func (c *config) recordArg(name string) bool {
+	return c.record != nil && c.record.MatchString(name)
+}
+
+// spanName renders the span-name template for the function described by d.
+func (c *config) spanName(d spanData) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := c.spanTpl.Execute(buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// recvName returns the name of the receiver type of fd, or the empty string
+// if fd is not a method.
+func recvName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return ""
+	}
+	typ := fd.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch index := typ.(type) {
+	case *ast.IndexExpr:
+		typ = index.X
+	case *ast.IndexListExpr:
+		typ = index.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
@@ -6,21 +6,138 @@
 // DO NOT UPDATE MANUALLY
 
 /*
-tracify adds vtrace annotations to all functions in the given packages that have
-a context as the first argument.
+tracify adds tracing annotations to all functions in the given packages that
+have a context as the first argument, checks for them and removes them.  Without
+a command, as in "tracify [-t] <packages>", tracify runs the inject command.
 
-TODO(mattr): We will eventually support various options like excluding certain
-functions or including specific information in the span name.
+The annotations begin a span that ends when the function returns, using one of
+the following backends, selected by the backend attribute of the -config file or
+the -backend flag:
+
+  vtrace - for functions that take a *context.T from v.io/v23/context, spans
+           are begun with vtrace.WithNewSpan.  This is the default.
+  otel   - for functions that take a context.Context from the standard
+           library, spans are begun with the Start method of the OpenTelemetry
+           tracer named after the package import path, from otel.Tracer.
+
+The context packages are recognized by their import paths, whatever they are
+imported as.  The import of the tracing package is added when it is needed.
+
+The functions to annotate, and the names of their spans, can be configured by a
+file passed to -config:
+
+  <tracify span-name="{{.Pkg}}.{{.Recv}}.{{.Func}}" backend="vtrace">
+    <include pkg="^v.io/x/ref/services/"/>
+    <exclude func="^String$"/>
+    <exclude recv="Stream$" func="^(Send|Recv)$"/>
+  </tracify>
+
+A function is annotated if there are no include rules or it matches one of them,
+and it matches no exclude rule.  A rule has regular expressions that must match
+the package import path (pkg), the function name (func) or the receiver type
+name (recv), which is empty for functions that are not methods; a rule matches
+if all of its expressions do.
+
+The span-name attribute, or the -span-name flag, is a text/template that renders
+the span names.  It is executed with the following fields:
+
+  .Pkg     - the package name
+  .PkgPath - the package import path
+  .Recv    - the receiver type name, or empty for functions
+  .Func    - the function name
+
+The default is "{{.Func}}", which names spans after the bare function name.
+
+The record attribute, or the -record flag, turns on recording of arguments and
+errors on spans.  It is a regular expression for the names of the arguments to
+record, after the context; only arguments of the predeclared boolean, numeric
+and string types are recorded, with vspan.Annotatef for vtrace and as
+OpenTelemetry attributes for otel.  When recording, the errors returned by
+functions whose last result is an error are recorded on the span when the
+function returns.  The results of such functions are named so that the error
+can be read, the error err, or err followed by a number if err is taken, and
+the others _.  Use "." to record all such arguments.
 
 Usage:
-   tracify [flags] [-t] [packages]
+   tracify [flags] <command>
+
+The tracify commands are:
+   check       Check that functions have tracing annotations.
+   inject      Add tracing annotations to functions.
+   remove      Remove tracing annotations from functions.
+   help        Display help for commands or topics
 
 The global flags are:
+ -backend=
+   the tracing backend, vtrace or otel, overriding the one in the -config file.
+ -config=
+   the file that selects the functions to annotate and names their spans.
  -metadata=<just specify -metadata to activate>
    Displays metadata for the program and exits.
+ -record=
+   the regular expression for the names of the arguments to record on spans,
+   along with returned errors, overriding the one in the -config file.
+ -span-name=
+   the text/template for span names, overriding the one in the -config file.
  -t=false
    include transitive dependencies of named packages.
  -time=false
    Dump timing information to stderr before exiting the program.
+
+Tracify check - Check that functions have tracing annotations.
+
+Check that all of the functions to annotate in the given packages begin with a
+span of the backend, and fail if any of them don't.  The functions without a
+span are printed.
+
+Usage:
+   tracify check [flags] [-t] [packages]
+
+Tracify inject - Add tracing annotations to functions.
+
+Add tracing annotations to the functions to annotate in the given packages.
+Functions that already begin with a span of the backend are left alone, so
+repeated runs are safe.
+
+Usage:
+   tracify inject [flags] [-t] [packages]
+
+Tracify remove - Remove tracing annotations from functions.
+
+Remove the spans of the backend that inject adds from all of the functions in
+the given packages, whether or not they are functions to annotate, and the
+imports of the packages they use if they are no longer used.  Results that
+inject named to record errors keep their names.  The spans are recognized by the
+comment that inject appends to their first statement, so spans that were
+written by hand are left alone.
+
+Usage:
+   tracify remove [flags] [-t] [packages]
+
+Tracify help - Display help for commands or topics
+
+Help with no args displays the usage of the parent command.
+
+Help with args displays the usage of the specified sub-command or help topic.
+
+"help ..." recursively displays help for all commands and topics.
+
+Usage:
+   tracify help [flags] [command/topic ...]
+
+[command/topic ...] optionally identifies a specific sub-command or help topic.
+
+The tracify help flags are:
+ -style=compact
+   The formatting style for help output:
+      compact   - Good for compact cmdline output.
+      full      - Good for cmdline output, shows all global flags.
+      godoc     - Good for godoc processing.
+      shortonly - Only output short description.
+   Override the default by setting the CMDLINE_STYLE environment variable.
+ -width=<terminal width>
+   Format output to this target width in runes, or unlimited if width < 0.
+   Defaults to the terminal width if available.  Override the default by setting
+   the CMDLINE_WIDTH environment variable.
 */
 package main
@@ -45,6 +45,24 @@ func (i *injector) inject(p token.Position, content string) error {
 	return err
 }
 
+// remove removes the content from p up to, but excluding, end.
+func (i *injector) remove(p, end token.Position) error {
+	toread := p.Offset - i.read
+	if _, err := io.CopyN(&i.w, i.r, int64(toread)); err != nil {
+		return err
+	}
+	toskip := end.Offset - p.Offset
+	i.read += toread + toskip
+	_, err := io.CopyN(ioutil.Discard, i.r, int64(toskip))
+	return err
+}
+
+// insert adds content before the byte at p.
+func (i *injector) insert(p token.Position, content string) error {
+	p.Offset--
+	return i.inject(p, content)
+}
+
 func (i *injector) format() error {
 	if _, err := io.Copy(&i.w, i.r); err != nil {
 		return err
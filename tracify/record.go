@@ -0,0 +1,155 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// scalar describes how an argument of a scalar type is recorded as an
+// OpenTelemetry attribute: attr is the attribute constructor, and conv the
+// conversion that the argument needs to be passed to it, if any.
+type scalar struct {
+	attr, conv string
+}
+
+// scalars are the types of the arguments that can be recorded on spans.
+var scalars = map[string]scalar{
+	"bool":    {"Bool", ""},
+	"string":  {"String", ""},
+	"int":     {"Int", ""},
+	"int64":   {"Int64", ""},
+	"float64": {"Float64", ""},
+	"int8":    {"Int64", "int64"},
+	"int16":   {"Int64", "int64"},
+	"int32":   {"Int64", "int64"},
+	"rune":    {"Int64", "int64"},
+	"uint":    {"Int64", "int64"},
+	"uint8":   {"Int64", "int64"},
+	"byte":    {"Int64", "int64"},
+	"uint16":  {"Int64", "int64"},
+	"uint32":  {"Int64", "int64"},
+	"uint64":  {"Int64", "int64"},
+	"uintptr": {"Int64", "int64"},
+	"float32": {"Float64", "float64"},
+}
+
+// spanArg is an argument that is recorded on a span.
+type spanArg struct {
+	// Name is the name of the argument.
+	Name string
+	// Attr is the OpenTelemetry attribute constructor for its type.
+	Attr string
+	// Value is the argument, converted to the type that Attr takes.
+	Value string
+}
+
+// recordedArgs returns the arguments of fd, after the context, that have
+// scalar types and match the record expression of the config.
+func recordedArgs(fd *ast.FuncDecl) []spanArg {
+	args := []spanArg{}
+	for i, field := range fd.Type.Params.List {
+		id, ok := field.Type.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		s, ok := scalars[id.Name]
+		if !ok {
+			continue
+		}
+		for j, name := range field.Names {
+			if (i == 0 && j == 0) || name.Name == "_" || !cfg.recordArg(name.Name) {
+				continue
+			}
+			arg := spanArg{Name: name.Name, Attr: s.attr, Value: name.Name}
+			if s.conv != "" {
+				arg.Value = s.conv + "(" + name.Name + ")"
+			}
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// edit replaces the source from pos up to, but excluding, end with text.  An
+// edit with no end inserts text before pos.
+type edit struct {
+	pos, end token.Pos
+	text     string
+}
+
+// errorResult returns the name of the last result of fd if it is an error,
+// or the empty string if it isn't.  It also returns the edits that name the
+// results of fd, if the error result isn't already named; the other results
+// are named _.
+func errorResult(fd *ast.FuncDecl) (string, []edit) {
+	results := fd.Type.Results
+	if results == nil || len(results.List) == 0 {
+		return "", nil
+	}
+	last := results.List[len(results.List)-1]
+	if id, ok := last.Type.(*ast.Ident); !ok || id.Name != "error" {
+		return "", nil
+	}
+	if n := len(last.Names); n > 0 {
+		id := last.Names[n-1]
+		if id.Name != "_" {
+			return id.Name, nil
+		}
+		name := freeName(identNames(fd), "err")
+		return name, []edit{{id.Pos(), id.End(), name}}
+	}
+	name := freeName(identNames(fd), "err")
+	if !results.Opening.IsValid() {
+		return name, []edit{
+			{pos: last.Type.Pos(), text: "(" + name + " "},
+			{pos: last.Type.End(), text: ")"},
+		}
+	}
+	edits := []edit{}
+	for _, field := range results.List {
+		text := "_ "
+		if field == last {
+			text = name + " "
+		}
+		edits = append(edits, edit{pos: field.Type.Pos(), text: text})
+	}
+	return name, edits
+}
+
+// identNames returns the names of the identifiers in n.
+func identNames(n ast.Node) map[string]bool {
+	names := map[string]bool{}
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			names[id.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+// freeName returns prefix, or prefix followed by a number, whichever comes
+// first that is not in used.
+func freeName(used map[string]bool, prefix string) string {
+	name := prefix
+	for i := 1; used[name]; i++ {
+		name = prefix + strconv.Itoa(i)
+	}
+	return name
+}
+
+// ArgsFormat returns the format that the recorded arguments of d are
+// annotated on vtrace spans with.
+func (d decl) ArgsFormat() string {
+	s := []string{}
+	for _, arg := range d.Args {
+		s = append(s, arg.Name+"=%v")
+	}
+	return strings.Join(s, ", ")
+}
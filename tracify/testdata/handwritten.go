@@ -0,0 +1,25 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	"fmt"
+
+	"v.io/v23/context"
+	"v.io/v23/vtrace"
+)
+
+func Hand(ctx *context.T) {
+	ctx, span := vtrace.WithNewSpan(ctx, "hand")
+	defer span.Finish()
+	fmt.Println(ctx)
+}
+
+func Injected(ctx *context.T) {
+	ctx, vspan := vtrace.WithNewSpan(ctx, "Injected") // tracify: DO NOT EDIT, MUST BE FIRST STATEMENTS
+	defer vspan.Finish()
+
+	fmt.Println(ctx)
+}
@@ -0,0 +1,22 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	stdctx "context"
+	"fmt"
+)
+
+type T struct{}
+
+func (t T) Foo(ctx stdctx.Context, a int) {
+	fmt.Println(a)
+}
+
+func Bar(ctx stdctx.Context) {}
+
+func NoContext(a int) {
+	fmt.Println(a)
+}
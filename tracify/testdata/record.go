@@ -0,0 +1,36 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+)
+
+func Error(ctx context.Context, attribute bool) error {
+	if attribute {
+		return errors.New("attribute")
+	}
+	return nil
+}
+
+func IntError(ctx context.Context, n int, s string, b []byte) (int, error) {
+	return n + len(s) + len(b), nil
+}
+
+func Named(ctx context.Context, i int32, _ string) (n int, _ error) {
+	return int(i), nil
+}
+
+func DeclaresErr(ctx context.Context) error {
+	err := errors.New("err")
+	return err
+}
+
+func NoError(ctx context.Context, u uint) codes.Code {
+	return codes.Code(u)
+}
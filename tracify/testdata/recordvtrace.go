@@ -0,0 +1,19 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	"errors"
+
+	"v.io/v23/context"
+)
+
+func IntError(ctx *context.T, n int, s string) (int, error) {
+	return n + len(s), errors.New("err")
+}
+
+func NoError(ctx *context.T, f float64) {
+	_ = f
+}
@@ -0,0 +1,32 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	"fmt"
+
+	"v.io/v23/context"
+)
+
+type Stream struct{}
+
+func (s *Stream) Send(ctx *context.T, b []byte) error {
+	fmt.Println(b)
+	return nil
+}
+
+func (s *Stream) Close(ctx *context.T) error {
+	return nil
+}
+
+type T struct{}
+
+func (t T) Send(ctx *context.T, b []byte) {
+	fmt.Println(b)
+}
+
+func (t T) String(ctx *context.T) string {
+	return "T"
+}
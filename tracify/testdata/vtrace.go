@@ -0,0 +1,34 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import (
+	"fmt"
+
+	v23ctx "v.io/v23/context"
+)
+
+type T struct{}
+
+func (t *T) Foo(ctx *v23ctx.T, a int) {
+	fmt.Println(a)
+}
+
+func Bar(ctx *v23ctx.T) {}
+
+// Baz has a comment.
+func Baz(ctx *v23ctx.T) {
+	// leading comment
+	x := 1
+	fmt.Println(x)
+}
+
+func Blank(_ *v23ctx.T) {
+	fmt.Println()
+}
+
+func NoContext(a int) {
+	fmt.Println(a)
+}
@@ -18,47 +18,186 @@ import (
 	"go/parser"
 	"go/token"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 
-	"text/template"
 	"v.io/x/lib/cmdline"
 	"v.io/x/lib/envvar"
 )
 
-var skipPackages = map[string]bool{
-	"v.io/v23/vtrace":                    true,
-	"v.io/v23/verror":                    true,
-	"v.io/x/ref/runtime/internal/vtrace": true,
+func main() {
+	os.Args = append(os.Args[:1], withDefaultCommand(os.Args[1:])...)
+	cmdline.Main(cmdTracify)
 }
 
-const vtracePackage = "\"v.io/v23/vtrace\""
-const contextPackage = "v.io/v23/context"
+// withDefaultCommand returns args, preceded by the inject command if they
+// don't name a command, so that "tracify [-t] <packages>" injects annotations.
+func withDefaultCommand(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			if i+1 == len(args) {
+				return args
+			}
+			return append([]string{cmdInject.Name}, args...)
+		case strings.HasPrefix(arg, "-"):
+			// Skip the value of a flag that is passed as the next argument.
+			name := strings.TrimLeft(arg, "-")
+			if f := flag.Lookup(name); f != nil {
+				if b, ok := f.Value.(boolFlag); !ok || !b.IsBoolFlag() {
+					i++
+				}
+			}
+		case arg == "help" || isCommand(arg):
+			return args
+		default:
+			return append([]string{cmdInject.Name}, args...)
+		}
+	}
+	// Without packages, the usage is printed.
+	return args
+}
 
-func main() {
-	cmdline.Main(cmdTracify)
+// boolFlag is implemented by the values of flags that take no argument.
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// isCommand returns true iff name is the name of a tracify command.
+func isCommand(name string) bool {
+	for _, child := range cmdTracify.Children {
+		if name == child.Name {
+			return true
+		}
+	}
+	return false
 }
 
 var (
-	transitive = flag.Bool("t", false, "include transitive dependencies of named packages.")
+	transitive  = flag.Bool("t", false, "include transitive dependencies of named packages.")
+	configFile  = flag.String("config", "", "the file that selects the functions to annotate and names their spans.")
+	spanName    = flag.String("span-name", "", "the text/template for span names, overriding the one in the -config file.")
+	backendName = flag.String("backend", "", "the tracing backend, vtrace or otel, overriding the one in the -config file.")
+	record      = flag.String("record", "", "the regular expression for the names of the arguments to record on spans, along with returned errors, overriding the one in the -config file.")
 )
 
+// cfg is the configuration loaded from the -config, -span-name, -backend and
+// -record flags.
+var cfg *config
+
 var cmdTracify = &cmdline.Command{
 	Name:  "tracify",
-	Short: "Add vtrace annotations to functions in the specified packages.",
+	Short: "Manage tracing annotations of functions in the specified packages.",
+	Long: `
+tracify adds tracing annotations to all functions in the given packages that
+have a context as the first argument, checks for them and removes them.  Without
+a command, as in "tracify [-t] <packages>", tracify runs the inject command.
+
+The annotations begin a span that ends when the function returns, using one of
+the following backends, selected by the backend attribute of the -config file
+or the -backend flag:
+
+  vtrace - for functions that take a *context.T from v.io/v23/context, spans
+           are begun with vtrace.WithNewSpan.  This is the default.
+  otel   - for functions that take a context.Context from the standard
+           library, spans are begun with the Start method of the OpenTelemetry
+           tracer named after the package import path, from otel.Tracer.
+
+The context packages are recognized by their import paths, whatever they are
+imported as.  The import of the tracing package is added when it is needed.
+
+The functions to annotate, and the names of their spans, can be configured
+by a file passed to -config:
+
+  <tracify span-name="{{.Pkg}}.{{.Recv}}.{{.Func}}" backend="vtrace">
+    <include pkg="^v.io/x/ref/services/"/>
+    <exclude func="^String$"/>
+    <exclude recv="Stream$" func="^(Send|Recv)$"/>
+  </tracify>
+
+A function is annotated if there are no include rules or it matches one of
+them, and it matches no exclude rule.  A rule has regular expressions that
+must match the package import path (pkg), the function name (func) or the
+receiver type name (recv), which is empty for functions that are not
+methods; a rule matches if all of its expressions do.
+
+The span-name attribute, or the -span-name flag, is a text/template that
+renders the span names.  It is executed with the following fields:
+
+  .Pkg     - the package name
+  .PkgPath - the package import path
+  .Recv    - the receiver type name, or empty for functions
+  .Func    - the function name
+
+The default is "{{.Func}}", which names spans after the bare function name.
+
+The record attribute, or the -record flag, turns on recording of arguments and
+errors on spans.  It is a regular expression for the names of the arguments to
+record, after the context; only arguments of the predeclared boolean, numeric
+and string types are recorded, with vspan.Annotatef for vtrace and as
+OpenTelemetry attributes for otel.  When recording, the errors returned by
+functions whose last result is an error are recorded on the span when the
+function returns.  The results of such functions are named so that the error
+can be read, the error err, or err followed by a number if err is taken, and
+the others _.  Use "." to record all such arguments.
+`,
+	Children: []*cmdline.Command{cmdCheck, cmdInject, cmdRemove},
+}
+
+var cmdCheck = &cmdline.Command{
+	Name:  "check",
+	Short: "Check that functions have tracing annotations.",
+	Long: `
+Check that all of the functions to annotate in the given packages begin with a
+span of the backend, and fail if any of them don't.  The functions without a
+span are printed.
+`,
+	ArgsName: "[-t] [packages]",
+	Runner:   cmdline.RunnerFunc(runCheck),
+}
+
+var cmdInject = &cmdline.Command{
+	Name:  "inject",
+	Short: "Add tracing annotations to functions.",
 	Long: `
-tracify adds vtrace annotations to all functions in the given packages that
-have a context as the first argument.
+Add tracing annotations to the functions to annotate in the given packages.
+Functions that already begin with a span of the backend are left alone, so
+repeated runs are safe.
+`,
+	ArgsName: "[-t] [packages]",
+	Runner:   cmdline.RunnerFunc(runInject),
+}
 
-TODO(mattr): We will eventually support various options like excluding certain functions
-or including specific information in the span name.
+var cmdRemove = &cmdline.Command{
+	Name:  "remove",
+	Short: "Remove tracing annotations from functions.",
+	Long: `
+Remove the spans of the backend that inject adds from all of the functions in
+the given packages, whether or not they are functions to annotate, and the
+imports of the packages they use if they are no longer used.  Results that
+inject named to record errors keep their names.  The spans are recognized by the
+comment that inject appends to their first statement, so spans that were
+written by hand are left alone.
 `,
 	ArgsName: "[-t] [packages]",
-	Runner:   cmdline.RunnerFunc(tracify),
+	Runner:   cmdline.RunnerFunc(runRemove),
 }
 
-// tracify adds vtrace spans to functions in the packages defined by args.
-func tracify(env *cmdline.Env, args []string) error {
+// fileFunc processes a single source file.  pkgPath is the import path of the
+// file's package.
+type fileFunc func(fset *token.FileSet, pkgPath, fname string, f *ast.File) error
+
+// tracify applies process to the files of the packages defined by args.
+func tracify(env *cmdline.Env, args []string, process fileFunc) error {
+	var err error
+	if cfg, err = loadConfig(*configFile, *spanName, *backendName, *record); err != nil {
+		return err
+	}
 	pkgs, err := readPackages(env, args)
 	if err != nil {
 		return err
@@ -72,71 +211,211 @@ func tracify(env *cmdline.Env, args []string) error {
 		}
 		pkgs = tPkgs
 	}
-	for _, pkg := range pkgs {
+	paths := []string{}
+	for path, pkg := range pkgs {
 		if pkg != nil {
-			if err := processPackage(pkg); err != nil {
-				return err
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := processPackage(pkgs[path], process); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runInject adds spans to functions in the packages defined by args.
+func runInject(env *cmdline.Env, args []string) error {
+	return tracify(env, args, processFile)
+}
+
+// runCheck fails if functions in the packages defined by args lack spans.
+func runCheck(env *cmdline.Env, args []string) error {
+	missing := 0
+	err := tracify(env, args, func(fset *token.FileSet, pkgPath, fname string, f *ast.File) error {
+		decls, err := findDecls(fset, pkgPath, f, false)
+		if err != nil {
+			return err
+		}
+		for _, d := range decls {
+			if d.span == nil {
+				fmt.Fprintf(env.Stdout, "%v: %s: no span\n", fset.Position(d.fd.Pos()), d.fd.Name.Name)
+				missing++
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d functions have no span", missing)
 	}
 	return nil
 }
 
-// processPackage processes a build package, rewriting any file in the package
-// to include vtrace annotations.
-func processPackage(pkg *build.Package) error {
+// runRemove removes spans from functions in the packages defined by args.
+func runRemove(env *cmdline.Env, args []string) error {
+	return tracify(env, args, removeFile)
+}
+
+// processPackage applies process to the files in a build package, in the
+// order of their names.
+func processPackage(pkg *build.Package, process fileFunc) error {
 	fset := token.NewFileSet()
 	pkgs, err := parser.ParseDir(fset, pkg.Dir, nil, parser.ParseComments)
 	if err != nil {
 		return err
 	}
 	for _, p := range pkgs {
-		for fname, f := range p.Files {
-			processFile(fset, fname, f)
+		fnames := []string{}
+		for fname := range p.Files {
+			fnames = append(fnames, fname)
+		}
+		sort.Strings(fnames)
+		for _, fname := range fnames {
+			if err := process(fset, pkg.ImportPath, fname, p.Files[fname]); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-var vtraceTpl = template.Must(template.New("vtrace").Parse(`
-	{{.CtxName}}, vspan := {{.VtraceName}}.WithNewSpan({{.CtxName}}, "{{.FuncName}}")
-	defer vspan.Finish()
-`))
-
 type decl struct {
-	pos        token.Position
-	CtxName    string
-	FuncName   string
-	VtraceName string
-}
+	pos      token.Position
+	CtxName  string
+	SpanName string
+	// TraceName is the name that the tracing package is imported as.
+	TraceName string
+	// Tracer is the import path of the package that declares the function,
+	// which names the OpenTelemetry tracer.
+	Tracer string
+	// Args are the arguments to record on the span, and Err the name of the
+	// error result to record, if any.
+	Args []spanArg
+	Err  string
+	// AttrPkg and CodesPkg are the names that the OpenTelemetry attribute
+	// and codes packages are imported as.
+	AttrPkg, CodesPkg string
 
-// processFile Processes a single source file, rewriting it to include vtrace
-// spans where necessary.
-func processFile(fset *token.FileSet, fname string, f *ast.File) error {
-	vtraceName := ""
-	for _, i := range f.Imports {
-		if i.Path.Value == vtracePackage {
-			if i.Name == nil {
-				vtraceName = "vtrace"
-			} else {
-				vtraceName = i.Name.Name
-			}
-		}
-	}
+	fd *ast.FuncDecl
+	// span holds the statements that begin the existing span in fd, if
+	// any.
+	span []ast.Stmt
+	// edits name the results of fd, so that the error result can be
+	// recorded.
+	edits []edit
+}
 
+// findDecls returns the functions in f that have the context of the backend
+// as the first argument.  Unless all is set, only the functions that the
+// config selects are returned.
+func findDecls(fset *token.FileSet, pkgPath string, f *ast.File, all bool) ([]decl, error) {
+	b := cfg.backend
+	_, traceName := importOf(f, b.pkgPath)
 	decls := []decl{}
-	args := translateTypes(fset, f.Imports, []string{"*context.T"})
+	ctxType := b.contextType(f)
+	if ctxType == "" {
+		return decls, nil
+	}
+	args := []string{ctxType}
 	for _, d := range f.Decls {
-		if fd, ok := d.(*ast.FuncDecl); ok {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil {
 			matches, names := checkParams(fset, fd.Type, args)
 			if !matches || len(names) == 0 || names[0] == "_" {
 				continue
 			}
-			decls = append(decls, decl{
+			data := spanData{
+				Pkg:     f.Name.Name,
+				PkgPath: pkgPath,
+				Recv:    recvName(fd),
+				Func:    fd.Name.Name,
+			}
+			if !all && !cfg.selects(data) {
+				continue
+			}
+			name, err := cfg.spanName(data)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", fset.Position(fd.Pos()), err)
+			}
+			d := decl{
 				pos:      fset.Position(fd.Body.Lbrace),
 				CtxName:  names[0],
-				FuncName: fd.Name.Name,
-			})
+				SpanName: name,
+				Tracer:   pkgPath,
+				fd:       fd,
+				span:     existingSpan(fd, b, traceName),
+			}
+			if cfg.recording() {
+				d.Args = recordedArgs(fd)
+				d.Err, d.edits = errorResult(fd)
+			}
+			decls = append(decls, d)
+		}
+	}
+	return decls, nil
+}
+
+// existingSpan returns the statements that begin a span of b in fd, in the
+// form that the template of b injects, or nil if fd doesn't begin with a
+// span.  traceName is the name that the tracing package is imported as.
+func existingSpan(fd *ast.FuncDecl, b *backend, traceName string) []ast.Stmt {
+	stmts := fd.Body.List
+	if traceName == "" || len(stmts) < 2 {
+		return nil
+	}
+	assign, ok := stmts[0].(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 2 || len(assign.Rhs) != 1 {
+		return nil
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok || !b.isStart(call.Fun, traceName) {
+		return nil
+	}
+	span, ok := assign.Lhs[1].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	n := 1
+	if annotate, ok := stmts[n].(*ast.ExprStmt); ok {
+		if call, ok := annotate.X.(*ast.CallExpr); ok && isSelector(call.Fun, span.Name, b.annotate) {
+			n++
+		}
+	}
+	if n >= len(stmts) {
+		return nil
+	}
+	finish, ok := stmts[n].(*ast.DeferStmt)
+	if !ok || !b.isFinish(finish.Call, span.Name) {
+		return nil
+	}
+	return stmts[:n+1]
+}
+
+// isSelector returns true if expr is x.sel.
+func isSelector(expr ast.Expr, x, sel string) bool {
+	s, ok := expr.(*ast.SelectorExpr)
+	if !ok || s.Sel.Name != sel {
+		return false
+	}
+	id, ok := s.X.(*ast.Ident)
+	return ok && id.Name == x
+}
+
+// processFile Processes a single source file, rewriting it to include spans
+// where necessary.  Functions that already begin with a span are skipped.
+func processFile(fset *token.FileSet, pkgPath, fname string, f *ast.File) error {
+	found, err := findDecls(fset, pkgPath, f, false)
+	if err != nil {
+		return err
+	}
+	decls := []decl{}
+	for _, d := range found {
+		if d.span == nil {
+			decls = append(decls, d)
 		}
 	}
 
@@ -145,15 +424,66 @@ func processFile(fset *token.FileSet, fname string, f *ast.File) error {
 		if err != nil {
 			return err
 		}
-		if vtraceName == "" {
-			if err := inj.inject(fset.Position(f.Name.End()), fmt.Sprintf("\nimport %s\n", vtracePackage)); err != nil {
+		b := cfg.backend
+		paths := []string{b.pkgPath}
+		for _, d := range decls {
+			if len(d.Args) > 0 && b.attrPath != "" {
+				paths = append(paths, b.attrPath)
+			}
+			if d.Err != "" && b.codesPath != "" {
+				paths = append(paths, b.codesPath)
+			}
+		}
+		// The imports that are missing are added after the package clause,
+		// where the injector first gets to.  They are renamed if their names
+		// are taken by other imports or identifiers in the file.
+		used := identNames(f)
+		for _, spec := range f.Imports {
+			if spec.Name == nil {
+				if p, err := strconv.Unquote(spec.Path.Value); err == nil {
+					used[path.Base(p)] = true
+				}
+			}
+		}
+		names := map[string]string{}
+		imports := ""
+		for _, p := range paths {
+			if _, ok := names[p]; ok {
+				continue
+			}
+			_, name := importOf(f, p)
+			if name == "" {
+				name = freeName(used, path.Base(p))
+				used[name] = true
+				if name == path.Base(p) {
+					imports += fmt.Sprintf("\nimport %q\n", p)
+				} else {
+					imports += fmt.Sprintf("\nimport %s %q\n", name, p)
+				}
+			}
+			names[p] = name
+		}
+		if imports != "" {
+			if err := inj.inject(fset.Position(f.Name.End()), imports); err != nil {
 				return err
 			}
-			vtraceName = "vtrace"
 		}
 		for _, d := range decls {
-			d.VtraceName = vtraceName
-			if err := inj.execute(d.pos, vtraceTpl, d); err != nil {
+			d.TraceName = names[b.pkgPath]
+			d.AttrPkg = names[b.attrPath]
+			d.CodesPkg = names[b.codesPath]
+			for _, e := range d.edits {
+				if e.end.IsValid() {
+					if err := inj.remove(fset.Position(e.pos), fset.Position(e.end)); err != nil {
+						return err
+					}
+					e.pos = e.end
+				}
+				if err := inj.insert(fset.Position(e.pos), e.text); err != nil {
+					return err
+				}
+			}
+			if err := inj.execute(d.pos, b.tpl, d); err != nil {
 				return err
 			}
 		}
@@ -164,6 +494,132 @@ func processFile(fset *token.FileSet, fname string, f *ast.File) error {
 	return nil
 }
 
+// removeFile removes the spans that inject added, which are marked by
+// spanComment, from a single source file, and the imports of the packages they
+// use if they are no longer used.  The names that inject gives to results are
+// left alone.
+func removeFile(fset *token.FileSet, pkgPath, fname string, f *ast.File) error {
+	decls, err := findDecls(fset, pkgPath, f, true)
+	if err != nil {
+		return err
+	}
+	src, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	tfile := fset.File(f.Pos())
+	// Each span is removed along with the white space that inject adds
+	// around it, unless that would remove comments or code.
+	ranges := []posRange{}
+	for _, d := range decls {
+		if d.span == nil || !hasSpanComment(f, tfile, d.span[0]) {
+			continue
+		}
+		start := d.span[0].Pos()
+		if lbrace := d.fd.Body.Lbrace + 1; isSpace(src, tfile, lbrace, start) {
+			start = lbrace
+		}
+		ranges = append(ranges, posRange{start, lineEnd(src, tfile, d.span[len(d.span)-1].End())})
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	// The imports come before the spans, in the order of the source.
+	imports := []posRange{}
+	for _, p := range cfg.backend.imports() {
+		spec, name := importOf(f, p)
+		if spec == nil || usedOutside(f, name, ranges) {
+			continue
+		}
+		r := posRange{spec.Pos(), spec.End()}
+		for _, d := range f.Decls {
+			if gd, ok := d.(*ast.GenDecl); ok && len(gd.Specs) == 1 && gd.Specs[0] == spec {
+				r = posRange{gd.Pos(), gd.End()}
+			}
+		}
+		r.end = lineEnd(src, tfile, r.end)
+		imports = append(imports, r)
+	}
+	sort.Sort(rangeSorter(imports))
+	ranges = append(imports, ranges...)
+
+	inj, err := newInjector(fname)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		if err := inj.remove(fset.Position(r.start), fset.Position(r.end)); err != nil {
+			return err
+		}
+	}
+	return inj.format()
+}
+
+// posRange is the range of source from start up to, but excluding, end.
+type posRange struct {
+	start, end token.Pos
+}
+
+type rangeSorter []posRange
+
+func (s rangeSorter) Len() int           { return len(s) }
+func (s rangeSorter) Less(i, j int) bool { return s[i].start < s[j].start }
+func (s rangeSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// hasSpanComment returns true if stmt is followed on its line by spanComment,
+// i.e. it begins a span that inject added.
+func hasSpanComment(f *ast.File, tfile *token.File, stmt ast.Stmt) bool {
+	line := tfile.Line(stmt.End())
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if c.Text == spanComment && c.Pos() >= stmt.End() && tfile.Line(c.Pos()) == line {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSpace returns true if the source between start and end is white space.
+func isSpace(src []byte, tfile *token.File, start, end token.Pos) bool {
+	return len(bytes.TrimSpace(src[tfile.Offset(start):tfile.Offset(end)])) == 0
+}
+
+// lineEnd returns the start of the line following pos if there is only white
+// space between them, and pos otherwise.
+func lineEnd(src []byte, tfile *token.File, pos token.Pos) token.Pos {
+	line := tfile.Line(pos)
+	if line >= tfile.LineCount() {
+		return pos
+	}
+	if next := tfile.LineStart(line + 1); isSpace(src, tfile, pos, next) {
+		return next
+	}
+	return pos
+}
+
+// usedOutside returns true if the package imported as name is used in f
+// outside of the given ranges.
+func usedOutside(f *ast.File, name string, ranges []posRange) bool {
+	used := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || used {
+			return !used
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && id.Name == name {
+			used = true
+			for _, r := range ranges {
+				if r.start <= sel.Pos() && sel.End() <= r.end {
+					used = false
+				}
+			}
+		}
+		return !used
+	})
+	return used
+}
+
 // readPackages resolves the user-supplied package patterns to a list of actual packages.
 // We just call out to 'go list' for this since there is actually a lot of subtlety
 // in resolving the patterns.
@@ -193,7 +649,7 @@ func readPackages(env *cmdline.Env, args []string) (map[string]*build.Package, e
 
 // addTransitive adds the transitive dependencies of pkg to packages.
 func addTransitive(packages map[string]*build.Package, pkg *build.Package, alsoTest bool) error {
-	if skipPackages[pkg.ImportPath] {
+	if cfg.backend.skips(pkg) {
 		return nil
 	}
 	if _, ok := packages[pkg.ImportPath]; ok {
@@ -202,7 +658,7 @@ func addTransitive(packages map[string]*build.Package, pkg *build.Package, alsoT
 	packages[pkg.ImportPath] = nil
 	foundCtx := false
 	for _, dep := range pkg.Imports {
-		if dep == contextPackage {
+		if dep == cfg.backend.ctxPath {
 			foundCtx = true
 			break
 		}
@@ -270,56 +726,3 @@ func checkParams(fset *token.FileSet, ftype *ast.FuncType, args []string) (bool,
 	}
 	return false, nil
 }
-
-// translateTypes uses the declared imports to change a list of queried types to their mapped versions.
-// For example if you call:
-//    translateTypes(fset, imports, "*testing.T")
-// and imports contains the import:
-//    import t "testing"
-// Then we will return:
-//    []string{"*t.T"}
-func translateTypes(fset *token.FileSet, imports []*ast.ImportSpec, types []string) []string {
-	namedImports := map[string]string{}
-	for _, i := range imports {
-		if i.Name != nil {
-			path := strings.Trim(i.Path.Value, "\"")
-			namedImports[path] = i.Name.Name
-		}
-	}
-	out := make([]string, len(types))
-	buf := &bytes.Buffer{}
-	for i, typ := range types {
-		out[i] = typ
-		if expr, err := parser.ParseExpr(typ); err == nil && changePackage(expr, namedImports) {
-			buf.Reset()
-			if err := format.Node(buf, fset, expr); err == nil {
-				out[i] = buf.String()
-			}
-		}
-	}
-	return out
-}
-
-// changePackage changes the package name of a type to the mapped name.
-// For example if you pass in the expr corresponding to "*testing.T" but
-// the file has declared:
-//   import t "testing"
-// then it will return the expr for "*t.T".
-// TODO(mattr): I'm not sure this catches all the cases.  If we find a breakage
-// we can fix it then.
-func changePackage(expr ast.Expr, namedImports map[string]string) bool {
-	switch e := expr.(type) {
-	case *ast.StarExpr:
-		return changePackage(e.X, namedImports)
-	case *ast.SelectorExpr:
-		if id, ok := e.X.(*ast.Ident); ok {
-			if name, has := namedImports[id.Name]; has {
-				id.Name = name
-			}
-			return true
-		}
-		return false
-	default:
-		return false
-	}
-}
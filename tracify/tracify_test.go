@@ -0,0 +1,172 @@
+// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testPackagePath is the import path that the test files are processed as.
+const testPackagePath = "v.io/x/devtools/tracify/testdata"
+
+func readFile(t *testing.T, fname string) string {
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+// processTestFile applies process to the file, and returns its source
+// afterwards.
+func processTestFile(t *testing.T, fname string, process fileFunc) string {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fname, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process(fset, testPackagePath, fname, f); err != nil {
+		t.Fatalf("%s: %v", fname, err)
+	}
+	return readFile(t, fname)
+}
+
+// countMissing returns the number of functions to annotate in the file that
+// don't begin with a span.
+func countMissing(t *testing.T, fname string) int {
+	missing := 0
+	processTestFile(t, fname, func(fset *token.FileSet, pkgPath, fname string, f *ast.File) error {
+		decls, err := findDecls(fset, pkgPath, f, false)
+		for _, d := range decls {
+			if d.span == nil {
+				missing++
+			}
+		}
+		return err
+	})
+	return missing
+}
+
+func TestInjectRemove(t *testing.T) {
+	tests := []struct {
+		// name is the name of the test file in testdata, without the .go
+		// suffix, and config the name of the config file in testdata, if any.
+		name, config              string
+		spanName, backend, record string
+		// injected and removed are the files in testdata with the source
+		// after inject, and after remove.  The defaults are name.golden and
+		// the test file itself.
+		injected, removed string
+	}{
+		{name: "vtrace"},
+		{name: "selected", config: "selected.xml"},
+		{name: "otel", spanName: "{{.Pkg}}.{{.Func}}", backend: "otel"},
+		{name: "record", backend: "otel", record: ".", removed: "record.removed"},
+		{name: "recordvtrace", record: "^(n|f)$", removed: "recordvtrace.removed"},
+		{name: "handwritten", injected: "handwritten.go", removed: "handwritten.removed"},
+	}
+	dir, err := ioutil.TempDir("", "tracify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range tests {
+		config := ""
+		if test.config != "" {
+			config = filepath.Join("testdata", test.config)
+		}
+		if cfg, err = loadConfig(config, test.spanName, test.backend, test.record); err != nil {
+			t.Fatal(err)
+		}
+		if test.injected == "" {
+			test.injected = test.name + ".golden"
+		}
+		if test.removed == "" {
+			test.removed = test.name + ".go"
+		}
+		src := readFile(t, filepath.Join("testdata", test.name+".go"))
+		fname := filepath.Join(dir, test.name+".go")
+		if err := ioutil.WriteFile(fname, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		injected := processTestFile(t, fname, processFile)
+		if want := readFile(t, filepath.Join("testdata", test.injected)); injected != want {
+			t.Errorf("%s: inject got:\n%s\nwant:\n%s", test.name, injected, want)
+		}
+		if got := processTestFile(t, fname, processFile); got != injected {
+			t.Errorf("%s: inject again got:\n%s\nwant:\n%s", test.name, got, injected)
+		}
+		if got := countMissing(t, fname); got != 0 {
+			t.Errorf("%s: got %d functions without spans after inject, want 0", test.name, got)
+		}
+		removed := processTestFile(t, fname, removeFile)
+		if want := readFile(t, filepath.Join("testdata", test.removed)); removed != want {
+			t.Errorf("%s: remove got:\n%s\nwant:\n%s", test.name, removed, want)
+		}
+	}
+}
+
+func TestWithDefaultCommand(t *testing.T) {
+	tests := []struct {
+		args, want []string
+	}{
+		{nil, nil},
+		{[]string{"p"}, []string{"inject", "p"}},
+		{[]string{"-t", "p", "q"}, []string{"inject", "-t", "p", "q"}},
+		{[]string{"-config", "c.xml", "p"}, []string{"inject", "-config", "c.xml", "p"}},
+		{[]string{"-config=c.xml", "p"}, []string{"inject", "-config=c.xml", "p"}},
+		{[]string{"--", "p"}, []string{"inject", "--", "p"}},
+		{[]string{"-t"}, []string{"-t"}},
+		{[]string{"-config", "check"}, []string{"-config", "check"}},
+		{[]string{"check", "p"}, []string{"check", "p"}},
+		{[]string{"-t", "remove", "p"}, []string{"-t", "remove", "p"}},
+		{[]string{"help", "inject"}, []string{"help", "inject"}},
+	}
+	for _, test := range tests {
+		if got, want := withDefaultCommand(test.args), test.want; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got %v, want %v", test.args, got, want)
+		}
+	}
+}
+
+func TestBackendSkips(t *testing.T) {
+	tests := []struct {
+		backend string
+		pkg     build.Package
+		want    bool
+	}{
+		{"vtrace", build.Package{ImportPath: "net/http", Goroot: true}, true},
+		{"vtrace", build.Package{ImportPath: "v.io/v23/vtrace"}, true},
+		{"vtrace", build.Package{ImportPath: "v.io/v23/verror"}, true},
+		{"vtrace", build.Package{ImportPath: "v.io/x/ref/runtime/internal/vtrace"}, true},
+		{"vtrace", build.Package{ImportPath: "v.io/v23/vtraceutil"}, false},
+		{"vtrace", build.Package{ImportPath: "v.io/x/ref/services/foo"}, false},
+		{"vtrace", build.Package{ImportPath: "go.opentelemetry.io/otel"}, false},
+		{"otel", build.Package{ImportPath: "context", Goroot: true}, true},
+		{"otel", build.Package{ImportPath: "go.opentelemetry.io/otel"}, true},
+		{"otel", build.Package{ImportPath: "go.opentelemetry.io/otel/attribute"}, true},
+		{"otel", build.Package{ImportPath: "go.opentelemetry.io/otel/internal/global"}, true},
+		{"otel", build.Package{ImportPath: "go.opentelemetry.io/otelfoo"}, false},
+		{"otel", build.Package{ImportPath: "v.io/v23/vtrace"}, false},
+	}
+	for _, test := range tests {
+		b, err := lookupBackend(test.backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := b.skips(&test.pkg), test.want; got != want {
+			t.Errorf("%s: %s got %v, want %v", test.backend, test.pkg.ImportPath, got, want)
+		}
+	}
+}